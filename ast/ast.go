@@ -1,9 +1,24 @@
 package ast
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MarkyMan4/yetti/token"
+)
 
 type Node interface {
 	ToString() string
+	Position() token.Position
+}
+
+// embedded in every node to keep track of where the node starts in the source
+type Pos struct {
+	Start token.Position
+}
+
+func (p *Pos) Position() token.Position {
+	return p.Start
 }
 
 type Statement interface {
@@ -18,6 +33,7 @@ type Expression interface {
 
 // expressions
 type IntegerLiteral struct {
+	Pos
 	Value int64
 }
 
@@ -28,6 +44,7 @@ func (i *IntegerLiteral) ToString() string {
 func (i *IntegerLiteral) expressionNode() {}
 
 type FloatLiteral struct {
+	Pos
 	Value float64
 }
 
@@ -38,6 +55,7 @@ func (i *FloatLiteral) ToString() string {
 func (i *FloatLiteral) expressionNode() {}
 
 type StringLiteral struct {
+	Pos
 	Value string
 }
 
@@ -47,7 +65,30 @@ func (i *StringLiteral) ToString() string {
 
 func (b *StringLiteral) expressionNode() {}
 
+// string with embedded expressions, e.g. "count: ${n + 1}", parts are evaluated and joined together
+type InterpolatedString struct {
+	Pos
+	Parts []Expression
+}
+
+func (i *InterpolatedString) ToString() string {
+	var sb strings.Builder
+
+	for _, part := range i.Parts {
+		if str, ok := part.(*StringLiteral); ok {
+			sb.WriteString(str.Value)
+		} else {
+			sb.WriteString("${" + part.ToString() + "}")
+		}
+	}
+
+	return sb.String()
+}
+
+func (i *InterpolatedString) expressionNode() {}
+
 type BooleanLiteral struct {
+	Pos
 	Value bool
 }
 
@@ -57,20 +98,44 @@ func (b *BooleanLiteral) ToString() string {
 
 func (b *BooleanLiteral) expressionNode() {}
 
+type NullLiteral struct {
+	Pos
+}
+
+func (n *NullLiteral) ToString() string {
+	return "null"
+}
+
+func (n *NullLiteral) expressionNode() {}
+
 type InfixExpression struct {
+	Pos
 	Left  Expression
 	Op    string
 	Right Expression
 }
 
 func (i *InfixExpression) ToString() string {
-	// fix this later, just returning empty string for now
-	return i.Left.ToString() + i.Op + i.Right.ToString()
+	return fmt.Sprintf("(%s %s %s)", i.Left.ToString(), i.Op, i.Right.ToString())
 }
 
 func (i *InfixExpression) expressionNode() {}
 
+// operator applied to a single expression, e.g. !done or -x
+type PrefixExpression struct {
+	Pos
+	Op    string
+	Right Expression
+}
+
+func (p *PrefixExpression) ToString() string {
+	return fmt.Sprintf("(%s%s)", p.Op, p.Right.ToString())
+}
+
+func (p *PrefixExpression) expressionNode() {}
+
 type IdentifierExpression struct {
+	Pos
 	Value string // name of identifier
 }
 
@@ -81,7 +146,9 @@ func (i *IdentifierExpression) ToString() string {
 func (i *IdentifierExpression) expressionNode() {}
 
 // function calls on an object, e.g. var a = "hello"; var b = s.substring(1, 3);
+// Function is an identifier when accessing a member instead, e.g. var port = config.port;
 type ObjectFunctionExpression struct {
+	Pos
 	Object   Expression
 	Function Expression
 }
@@ -90,9 +157,12 @@ func (o *ObjectFunctionExpression) ToString() string {
 	return fmt.Sprintf("%s.%s", o.Object.ToString(), o.Function.ToString())
 }
 
+// calling a function on an object can be used as both a statement and expression
 func (o *ObjectFunctionExpression) expressionNode() {}
+func (o *ObjectFunctionExpression) statementNode()  {}
 
 type ArrayExpression struct {
+	Pos
 	Items []Expression
 }
 
@@ -111,8 +181,33 @@ func (a *ArrayExpression) ToString() string {
 
 func (a *ArrayExpression) expressionNode() {}
 
+// map literal, e.g. {"a": 1, "b": 2}
+// keys and values are stored in separate lists so the order they were written in is kept
+type MapExpression struct {
+	Pos
+	Keys   []Expression
+	Values []Expression
+}
+
+func (m *MapExpression) ToString() string {
+	mapStr := ""
+
+	for i := range m.Keys {
+		mapStr += fmt.Sprintf("%s: %s", m.Keys[i].ToString(), m.Values[i].ToString())
+		if i < len(m.Keys)-1 {
+			mapStr += ", "
+		}
+	}
+
+	return fmt.Sprintf("{%s}", mapStr)
+}
+
+func (m *MapExpression) expressionNode() {}
+
 // e.g. var arr = [1,2,3]; var i = arr[0];
+// also used for maps, e.g. var m = {"a": 1}; var a = m["a"];
 type ArrayIndexExpression struct {
+	Pos
 	Arr   Expression
 	Index Expression
 }
@@ -125,6 +220,7 @@ func (a *ArrayIndexExpression) expressionNode() {}
 
 // statements
 type VarStatement struct {
+	Pos
 	Identifier string
 	Value      Expression
 }
@@ -135,20 +231,23 @@ func (l *VarStatement) ToString() string {
 
 func (l *VarStatement) statementNode() {}
 
+// target is an identifier, index or member, e.g. x = 1; xs[0] += 1; config.port = 80;
 type AssignStatement struct {
-	Identifier string
-	AssignOp   string // assignment operators are =, +=, -=, *=, /=
-	Value      Expression
+	Pos
+	Target   Expression
+	AssignOp string // assignment operators are =, +=, -=, *=, /=
+	Value    Expression
 }
 
 func (a *AssignStatement) ToString() string {
-	return fmt.Sprintf("%s %s %s;", a.Identifier, a.AssignOp, a.Value.ToString())
+	return fmt.Sprintf("%s %s %s;", a.Target.ToString(), a.AssignOp, a.Value.ToString())
 }
 
 func (a *AssignStatement) statementNode() {}
 
 // function invocation
 type FunctionCall struct {
+	Pos
 	Name string
 	Args []Expression
 }
@@ -172,8 +271,53 @@ func (fc *FunctionCall) ToString() string {
 func (fc *FunctionCall) expressionNode() {}
 func (fc *FunctionCall) statementNode()  {}
 
+// call on the result of an expression, e.g. makeCounter()()
+type CallExpression struct {
+	Pos
+	Function Expression
+	Args     []Expression
+}
+
+func (ce *CallExpression) ToString() string {
+	argsStr := ""
+
+	for i := range ce.Args {
+		argsStr += ce.Args[i].ToString()
+		if i < len(ce.Args)-1 {
+			argsStr += ", "
+		}
+	}
+
+	return fmt.Sprintf("%s(%s)", ce.Function.ToString(), argsStr)
+}
+
+func (ce *CallExpression) expressionNode() {}
+func (ce *CallExpression) statementNode()  {}
+
+// anonymous function, e.g. fun(x) { return x * 2; }
+type FunctionLiteral struct {
+	Pos
+	Args       []string
+	Statements []Statement
+}
+
+func (fl *FunctionLiteral) ToString() string {
+	funcStr := fmt.Sprintf("fun(%s) { ", strings.Join(fl.Args, ", "))
+
+	for i := range fl.Statements {
+		funcStr += fl.Statements[i].ToString() + " "
+	}
+
+	funcStr += "}"
+
+	return funcStr
+}
+
+func (fl *FunctionLiteral) expressionNode() {}
+
 // while loop
 type WhileStatement struct {
+	Pos
 	Condition  Expression
 	Statements []Statement
 }
@@ -192,12 +336,79 @@ func (ws *WhileStatement) ToString() string {
 
 func (ws *WhileStatement) statementNode() {}
 
-// if statement
-type IfStatement struct {
+// c-style for loop, e.g. for(var i = 0; i < 10; i += 1) { ... }
+// Init and Update are optional and will be nil if they are omitted
+type ForStatement struct {
+	Pos
+	Init       Statement
 	Condition  Expression
+	Update     Statement
+	Statements []Statement
+}
+
+func (fs *ForStatement) ToString() string {
+	forAsStr := "for("
+
+	if fs.Init != nil {
+		forAsStr += fs.Init.ToString()
+	} else {
+		forAsStr += ";"
+	}
+
+	if fs.Condition != nil {
+		forAsStr += " " + fs.Condition.ToString()
+	}
+
+	forAsStr += ";"
+
+	if fs.Update != nil {
+		forAsStr += " " + strings.TrimSuffix(fs.Update.ToString(), ";")
+	}
+
+	forAsStr += ") { "
+
+	for i := range fs.Statements {
+		forAsStr += fs.Statements[i].ToString() + " "
+	}
+
+	forAsStr += "}"
+
+	return forAsStr
+}
+
+func (fs *ForStatement) statementNode() {}
+
+// for loop over the items in a collection, e.g. for(x in xs) { ... }
+type ForInStatement struct {
+	Pos
+	Identifier string
+	Collection Expression
 	Statements []Statement
 }
 
+func (fs *ForInStatement) ToString() string {
+	forAsStr := fmt.Sprintf("for(%s in %s) { ", fs.Identifier, fs.Collection.ToString())
+
+	for i := range fs.Statements {
+		forAsStr += fs.Statements[i].ToString() + " "
+	}
+
+	forAsStr += "}"
+
+	return forAsStr
+}
+
+func (fs *ForInStatement) statementNode() {}
+
+// if statement
+// an else if is stored as a single IfStatement in the Alternative of the previous if
+type IfStatement struct {
+	Pos
+	Condition   Expression
+	Statements  []Statement
+	Alternative []Statement // statements in the else branch, nil if there is no else
+}
+
 func (is *IfStatement) ToString() string {
 	ifAsStr := fmt.Sprintf("if(%s) { ", is.Condition.ToString())
 
@@ -207,6 +418,16 @@ func (is *IfStatement) ToString() string {
 
 	ifAsStr += "}"
 
+	if is.Alternative != nil {
+		ifAsStr += " else { "
+
+		for i := range is.Alternative {
+			ifAsStr += is.Alternative[i].ToString() + " "
+		}
+
+		ifAsStr += "}"
+	}
+
 	return ifAsStr
 }
 
@@ -214,6 +435,7 @@ func (is *IfStatement) statementNode() {}
 
 // function definition
 type FunctionDef struct {
+	Pos
 	Name       string
 	Args       []string // list of identifiers
 	Statements []Statement
@@ -246,6 +468,7 @@ func (fd *FunctionDef) statementNode() {}
 
 // return statement
 type ReturnStatement struct {
+	Pos
 	ReturnVal Expression
 }
 
@@ -255,6 +478,88 @@ func (rs *ReturnStatement) ToString() string {
 
 func (rs *ReturnStatement) statementNode() {}
 
+// break statement, exits the innermost loop
+type BreakStatement struct {
+	Pos
+}
+
+func (bs *BreakStatement) ToString() string {
+	return "break;"
+}
+
+func (bs *BreakStatement) statementNode() {}
+
+// continue statement, skips to the next iteration of the innermost loop
+type ContinueStatement struct {
+	Pos
+}
+
+func (cs *ContinueStatement) ToString() string {
+	return "continue;"
+}
+
+func (cs *ContinueStatement) statementNode() {}
+
+// throw statement, raises an error from a string or a map with a message and kind
+type ThrowStatement struct {
+	Pos
+	Value Expression
+}
+
+func (ts *ThrowStatement) ToString() string {
+	return fmt.Sprintf("throw %s;", ts.Value.ToString())
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+// try/catch, e.g. try { ... } catch(e) { ... }
+// the identifier for the caught error is optional and will be empty if it's left out
+type TryStatement struct {
+	Pos
+	Statements      []Statement
+	CatchIdentifier string
+	CatchStatements []Statement
+}
+
+func (ts *TryStatement) ToString() string {
+	tryAsStr := "try { "
+
+	for i := range ts.Statements {
+		tryAsStr += ts.Statements[i].ToString() + " "
+	}
+
+	tryAsStr += "} catch"
+
+	if ts.CatchIdentifier != "" {
+		tryAsStr += fmt.Sprintf("(%s)", ts.CatchIdentifier)
+	}
+
+	tryAsStr += " { "
+
+	for i := range ts.CatchStatements {
+		tryAsStr += ts.CatchStatements[i].ToString() + " "
+	}
+
+	tryAsStr += "}"
+
+	return tryAsStr
+}
+
+func (ts *TryStatement) statementNode() {}
+
+// import statement, e.g. import "lib/strings.yti" as s;
+type ImportStatement struct {
+	Pos
+	Path  string
+	Alias string
+}
+
+func (is *ImportStatement) ToString() string {
+	return fmt.Sprintf("import \"%s\" as %s;", is.Path, is.Alias)
+}
+
+func (is *ImportStatement) statementNode() {}
+
 // program is a list of statements
 type Program struct {
 	Statements []Statement
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/MarkyMan4/yetti"
+	"github.com/MarkyMan4/yetti/compiler"
+	"github.com/MarkyMan4/yetti/diagnostics"
+	"github.com/MarkyMan4/yetti/object"
+	"github.com/MarkyMan4/yetti/repl"
+	"github.com/MarkyMan4/yetti/vm"
+)
+
+// compile the program in a file and run it on the VM, args are the arguments given to the program
+func runVM(filename string, args []string, maxDepth int, searchPath []string) error {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("error reading file %s: %w", filename, err)
+	}
+
+	prog, err := yetti.Parse(filename, string(src))
+	if err != nil {
+		return err
+	}
+
+	bytecode, err := compiler.NewCompiler(filename).Compile(prog)
+
+	var compileErr *compiler.CompileError
+	if errors.As(err, &compileErr) {
+		return errors.New(diagnostics.Format(filename, string(src), compileErr.Pos, compileErr.Message))
+	} else if err != nil {
+		return err
+	}
+
+	rt := object.DefaultRuntime()
+	rt.Args = args
+	rt.MaxCallDepth = maxDepth
+	rt.SearchPath = searchPath
+
+	// files the program left open are closed once it ends
+	errObj := vm.NewVM(rt).Run(bytecode)
+	closeErr := rt.CloseFiles()
+
+	if errObj != nil {
+		if errObj.Exit {
+			return &yetti.ExitError{Code: errObj.Code}
+		}
+
+		return yetti.NewRuntimeError(errObj, filename, string(src))
+	}
+
+	return closeErr
+}
+
+func main() {
+	engine := flag.String("engine", "eval", "how to run the program, either eval to walk the syntax tree or vm to compile it to bytecode")
+	maxDepth := flag.Int("max-depth", object.DefaultMaxCallDepth, "maximum depth of nested function calls")
+	flag.Parse()
+
+	if *engine != "eval" && *engine != "vm" {
+		fmt.Fprintf(os.Stderr, "unknown engine %s, expected eval or vm\n", *engine)
+		os.Exit(2)
+	}
+
+	// start an interactive session if no file is given
+	if flag.NArg() < 1 {
+		os.Exit(repl.Start(os.Stdin, os.Stdout))
+	}
+
+	// extra directories to search for imports, separated like PATH
+	searchPath := filepath.SplitList(os.Getenv("YETTI_PATH"))
+
+	// arguments after the file are passed to the program
+	filename, args := flag.Arg(0), flag.Args()[1:]
+
+	var err error
+	if *engine == "vm" {
+		err = runVM(filename, args, *maxDepth, searchPath)
+	} else {
+		in := yetti.NewInterpreter()
+		in.SetArgs(args)
+		in.SetMaxCallDepth(*maxDepth)
+		in.SetSearchPath(searchPath)
+		err = in.RunFile(filename)
+
+		if closeErr := in.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	var exitErr *yetti.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.Code)
+	}
+
+	// syntax errors and errors the program didn't catch are printed with the line they happened on
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,292 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+type Instructions []byte
+
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota
+	OpNull
+	OpTrue
+	OpFalse
+	OpPop
+
+	// binary operators, these have the same meaning as in the evaluator
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpEqual
+	OpNotEqual
+	OpLess
+	OpLessEqual
+	OpGreater
+	OpGreaterEqual
+
+	// unary operators
+	OpMinus
+	OpPlus
+	OpNot
+
+	// && and ||, the left side jumps to the end if it decides the result, otherwise the right side is checked
+	OpLogicalLeft
+	OpLogicalRight
+
+	OpJump
+	OpJumpIfFalse
+
+	// variables are looked up by how many scopes up they are and their slot in that scope
+	OpGetVar
+	OpGetAssignTarget
+	OpGetFunction
+	OpDefineVar
+	OpSetVar
+	OpPushScope
+	OpPopScope
+
+	OpArray
+	OpMap
+	OpCheckMapKey
+	OpIndex
+	OpCheckIndexTarget
+	OpSetIndex
+	OpGetMember
+	OpCheckMemberTarget
+	OpSetMember
+	OpInterpolate
+
+	OpClosure
+	OpCheckFunction
+	OpGetMethod
+	OpCall
+	OpCallMethod
+	OpReturn
+
+	OpIterStart
+	OpIterNext
+
+	OpTry
+	OpEndTry
+	OpThrow
+
+	OpImport
+)
+
+type Definition struct {
+	Name          string
+	OperandWidths []int // width of each operand in bytes
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:          {"OpConstant", []int{2}},
+	OpNull:              {"OpNull", []int{}},
+	OpTrue:              {"OpTrue", []int{}},
+	OpFalse:             {"OpFalse", []int{}},
+	OpPop:               {"OpPop", []int{}},
+	OpAdd:               {"OpAdd", []int{}},
+	OpSub:               {"OpSub", []int{}},
+	OpMul:               {"OpMul", []int{}},
+	OpDiv:               {"OpDiv", []int{}},
+	OpEqual:             {"OpEqual", []int{}},
+	OpNotEqual:          {"OpNotEqual", []int{}},
+	OpLess:              {"OpLess", []int{}},
+	OpLessEqual:         {"OpLessEqual", []int{}},
+	OpGreater:           {"OpGreater", []int{}},
+	OpGreaterEqual:      {"OpGreaterEqual", []int{}},
+	OpMinus:             {"OpMinus", []int{}},
+	OpPlus:              {"OpPlus", []int{}},
+	OpNot:               {"OpNot", []int{}},
+	OpLogicalLeft:       {"OpLogicalLeft", []int{1, 2}},     // 0 for &&, 1 for ||, and the address to jump to
+	OpLogicalRight:      {"OpLogicalRight", []int{1}},       // 0 for &&, 1 for ||
+	OpJump:              {"OpJump", []int{2}},               // address
+	OpJumpIfFalse:       {"OpJumpIfFalse", []int{2}},        // address
+	OpGetVar:            {"OpGetVar", []int{1, 2}},          // scope depth and slot
+	OpGetAssignTarget:   {"OpGetAssignTarget", []int{1, 2}}, // scope depth and slot
+	OpGetFunction:       {"OpGetFunction", []int{1, 2}},     // scope depth and slot, falls back to the builtin with the same name
+	OpDefineVar:         {"OpDefineVar", []int{2}},          // slot in the current scope
+	OpSetVar:            {"OpSetVar", []int{1, 1, 2}},       // assignment operator, scope depth and slot
+	OpPushScope:         {"OpPushScope", []int{2}},          // index of the scope in the scope table
+	OpPopScope:          {"OpPopScope", []int{}},            //
+	OpArray:             {"OpArray", []int{2}},              // number of items
+	OpMap:               {"OpMap", []int{2}},                // number of key value pairs
+	OpCheckMapKey:       {"OpCheckMapKey", []int{}},         //
+	OpIndex:             {"OpIndex", []int{}},               //
+	OpCheckIndexTarget:  {"OpCheckIndexTarget", []int{2}},   // constant with the target as a string for errors
+	OpSetIndex:          {"OpSetIndex", []int{1, 2}},        // assignment operator and target constant
+	OpGetMember:         {"OpGetMember", []int{2}},          // constant with the member name
+	OpCheckMemberTarget: {"OpCheckMemberTarget", []int{2}},  // member name constant
+	OpSetMember:         {"OpSetMember", []int{1, 2, 2}},    // assignment operator, member name constant and target constant
+	OpInterpolate:       {"OpInterpolate", []int{2}},        // number of parts
+	OpClosure:           {"OpClosure", []int{2}},            // constant with the compiled function
+	OpCheckFunction:     {"OpCheckFunction", []int{2}},      // constant with the name of the function for errors
+	OpGetMethod:         {"OpGetMethod", []int{2}},          // constant with the method name
+	OpCall:              {"OpCall", []int{1, 2}},            // number of arguments and function name constant
+	OpCallMethod:        {"OpCallMethod", []int{1, 2}},      // number of arguments and method name constant
+	OpReturn:            {"OpReturn", []int{}},              //
+	OpIterStart:         {"OpIterStart", []int{}},           //
+	OpIterNext:          {"OpIterNext", []int{2}},           // address to jump to when there are no items left
+	OpTry:               {"OpTry", []int{2}},                // address of the catch block
+	OpEndTry:            {"OpEndTry", []int{}},              //
+	OpThrow:             {"OpThrow", []int{}},               //
+	OpImport:            {"OpImport", []int{2}},             // constant with the path to import
+}
+
+// operators of the binary and unary opcodes, the VM passes these to the evaluator's operations
+var operators = map[Opcode]string{
+	OpAdd:          "+",
+	OpSub:          "-",
+	OpMul:          "*",
+	OpDiv:          "/",
+	OpEqual:        "==",
+	OpNotEqual:     "!=",
+	OpLess:         "<",
+	OpLessEqual:    "<=",
+	OpGreater:      ">",
+	OpGreaterEqual: ">=",
+	OpMinus:        "-",
+	OpPlus:         "+",
+	OpNot:          "!",
+}
+
+func Operator(op Opcode) string {
+	return operators[op]
+}
+
+// assignment operators are encoded as a single byte operand
+var assignOps = []string{"=", "+=", "-=", "*=", "/="}
+
+func AssignOp(operand int) string {
+	return assignOps[operand]
+}
+
+// && is encoded as 0 and || as 1
+var logicalOps = []string{"&&", "||"}
+
+func LogicalOp(operand int) string {
+	return logicalOps[operand]
+}
+
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return def, nil
+}
+
+// describe what an operand counts, used in the error when a program goes over the limit of an operand
+func operandLimit(op Opcode, operand int) string {
+	switch op {
+	case OpJump, OpJumpIfFalse, OpLogicalLeft, OpIterNext, OpTry:
+		return "size of the compiled function in bytes"
+	case OpCall, OpCallMethod:
+		if operand == 0 {
+			return "number of arguments in a function call"
+		}
+	case OpArray:
+		return "number of items in an array"
+	case OpMap:
+		return "number of entries in a map"
+	case OpInterpolate:
+		return "number of parts in an interpolated string"
+	case OpGetVar, OpGetAssignTarget, OpGetFunction, OpSetVar:
+		// the depth is the second to last operand and the slot is the last
+		if operand == len(definitions[op].OperandWidths)-2 {
+			return "depth of nested scopes"
+		}
+
+		return "number of variables in a scope"
+	case OpDefineVar:
+		return "number of variables in a scope"
+	case OpPushScope:
+		return "number of scopes"
+	}
+
+	return "number of constants"
+}
+
+// encode an instruction
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	instructionLen := 1
+	for _, w := range def.OperandWidths {
+		instructionLen += w
+	}
+
+	instruction := make([]byte, instructionLen)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		switch def.OperandWidths[i] {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+
+		offset += def.OperandWidths[i]
+	}
+
+	return instruction
+}
+
+// decode the operands of an instruction, returns the operands and the number of bytes they take up
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ins[offset])
+		}
+
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// human readable listing of the instructions, used for debugging and tests
+func (ins Instructions) String() string {
+	var sb strings.Builder
+
+	for i := 0; i < len(ins); {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&sb, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&sb, "%04d %s", i, def.Name)
+
+		for _, o := range operands {
+			fmt.Fprintf(&sb, " %d", o)
+		}
+
+		sb.WriteString("\n")
+		i += 1 + read
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,717 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/MarkyMan4/yetti/ast"
+	"github.com/MarkyMan4/yetti/object"
+	"github.com/MarkyMan4/yetti/token"
+)
+
+// the result of compiling a file
+type Bytecode struct {
+	File      string // path of the file that was compiled, imports are resolved relative to it
+	Constants []object.Object
+	Scopes    []*Scope // scopes created by the VM are described by an index into this table
+	Main      *CompiledFunction
+}
+
+// variables declared in a scope, each variable gets a slot in the order it was declared
+type Scope struct {
+	Names []string
+}
+
+// a function is compiled once and a closure is created each time the function definition is run
+type CompiledFunction struct {
+	Name         string
+	Instructions Instructions
+	Positions    map[int]token.Position // position of the node each instruction was compiled from, by offset
+	NumArgs      int
+	Scope        int // scope created for each call, arguments are the first slots
+	Bytecode     *Bytecode
+}
+
+func (f *CompiledFunction) Type() string {
+	return object.FUNCTION_OBJ
+}
+
+func (f *CompiledFunction) ToString() string {
+	return "function"
+}
+
+type blockKind int
+
+const (
+	loopBlock  blockKind = iota // break and continue jump to the innermost loop
+	tryBlock                    // a try block has a handler that needs to be removed when jumping out of it
+	scopeBlock                  // a scope that needs to be popped when jumping out of it
+	iterBlock                   // the iterator of a for-in loop is kept on the stack while the loop runs
+)
+
+type block struct {
+	kind          blockKind
+	breakJumps    []int
+	continueJumps []int
+}
+
+// state for the function that is currently being compiled
+type function struct {
+	instructions Instructions
+	positions    map[int]token.Position
+	blocks       []*block
+	main         bool  // top-level code of the file
+	returnJumps  []int // a return at the top level skips the rest of the statement it's in
+	parent       *function
+}
+
+type scope struct {
+	index  int // index in the scope table
+	info   *Scope
+	slots  map[string]int
+	parent *scope
+}
+
+// raised when a program is too large for the VM, e.g. a function call with more arguments than can be encoded
+type CompileError struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e *CompileError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+type constantKey struct {
+	typ   string
+	value interface{}
+}
+
+type Compiler struct {
+	bytecode  *Bytecode
+	constants map[constantKey]int
+	fn        *function
+	scope     *scope
+	pos       token.Position // position of the node being compiled, recorded for each instruction
+	err       *CompileError  // first operand that was too large to encode
+}
+
+func NewCompiler(file string) *Compiler {
+	return &Compiler{
+		bytecode:  &Bytecode{File: file},
+		constants: map[constantKey]int{},
+	}
+}
+
+// compile a program, returns a *CompileError if the program is too large to be encoded as bytecode
+func (c *Compiler) Compile(prog *ast.Program) (*Bytecode, error) {
+	c.fn = &function{positions: map[int]token.Position{}, main: true}
+	scopeIdx := c.enterScope()
+	c.hoist(prog.Statements)
+
+	for _, stmt := range prog.Statements {
+		c.fn.returnJumps = nil
+		c.compile(stmt)
+
+		for _, jump := range c.fn.returnJumps {
+			c.replaceOperands(jump, len(c.fn.instructions))
+		}
+
+		c.emit(OpPop)
+	}
+
+	c.emit(OpNull)
+	c.emit(OpReturn)
+	c.leaveScope()
+
+	c.bytecode.Main = &CompiledFunction{
+		Name:         "main",
+		Instructions: c.fn.instructions,
+		Positions:    c.fn.positions,
+		Scope:        scopeIdx,
+		Bytecode:     c.bytecode,
+	}
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return c.bytecode, nil
+}
+
+// compile a node, every statement and expression leaves exactly one value on the stack
+func (c *Compiler) compile(node ast.Node) {
+	// instructions are tagged with the innermost node that has a position, like errors in the evaluator
+	pos := c.pos
+	if node.Position().IsValid() {
+		c.pos = node.Position()
+	}
+	defer func() { c.pos = pos }()
+
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		c.emit(OpConstant, c.constant(object.INTEGER_OBJ, node.Value, &object.IntegerObject{Value: node.Value}))
+	case *ast.FloatLiteral:
+		c.emit(OpConstant, c.constant(object.FLOAT_OBJ, node.Value, &object.FloatObject{Value: node.Value}))
+	case *ast.StringLiteral:
+		c.emit(OpConstant, c.stringConstant(node.Value))
+	case *ast.InterpolatedString:
+		for _, part := range node.Parts {
+			c.compile(part)
+		}
+
+		c.emit(OpInterpolate, len(node.Parts))
+	case *ast.BooleanLiteral:
+		if node.Value {
+			c.emit(OpTrue)
+		} else {
+			c.emit(OpFalse)
+		}
+	case *ast.NullLiteral:
+		c.emit(OpNull)
+	case *ast.ArrayExpression:
+		for _, item := range node.Items {
+			c.compile(item)
+		}
+
+		c.emit(OpArray, len(node.Items))
+	case *ast.MapExpression:
+		// each key is checked before its value is evaluated
+		for i := range node.Keys {
+			c.compile(node.Keys[i])
+			c.emit(OpCheckMapKey)
+			c.compile(node.Values[i])
+		}
+
+		c.emit(OpMap, len(node.Keys))
+	case *ast.ArrayIndexExpression:
+		c.compile(node.Arr)
+		c.compile(node.Index)
+		c.emit(OpIndex)
+	case *ast.IdentifierExpression:
+		depth, slot := c.resolve(node.Value)
+		c.emit(OpGetVar, depth, slot)
+	case *ast.PrefixExpression:
+		c.compile(node.Right)
+		c.emit(prefixOpcodes[node.Op])
+	case *ast.InfixExpression:
+		c.compileInfix(node)
+	case *ast.VarStatement:
+		c.compile(node.Value)
+		c.emit(OpDefineVar, c.scope.declare(node.Identifier))
+	case *ast.AssignStatement:
+		c.compileAssignment(node)
+	case *ast.IfStatement:
+		c.compileIf(node)
+	case *ast.WhileStatement:
+		c.compileWhile(node)
+	case *ast.ForStatement:
+		c.compileFor(node)
+	case *ast.ForInStatement:
+		c.compileForIn(node)
+	case *ast.FunctionDef:
+		slot := c.scope.declare(node.Name)
+		c.compileFunction(node.Name, node.Args, node.Statements)
+		c.emit(OpDefineVar, slot)
+		c.emit(OpPop)
+		c.emit(OpNull)
+	case *ast.FunctionLiteral:
+		c.compileFunction("", node.Args, node.Statements)
+	case *ast.FunctionCall:
+		depth, slot := c.resolve(node.Name)
+		c.emit(OpGetFunction, depth, slot)
+		c.compileCall(node.Args, OpCall, node.Name)
+	case *ast.CallExpression:
+		c.compile(node.Function)
+		name := c.stringConstant(node.Function.ToString())
+		c.emit(OpCheckFunction, name)
+		c.compileCall(node.Args, OpCall, node.Function.ToString())
+	case *ast.ObjectFunctionExpression:
+		c.compile(node.Object)
+
+		if member, ok := node.Function.(*ast.IdentifierExpression); ok {
+			c.emit(OpGetMember, c.stringConstant(member.Value))
+			break
+		}
+
+		fnCall := node.Function.(*ast.FunctionCall)
+		c.emit(OpGetMethod, c.stringConstant(fnCall.Name))
+		c.compileCall(fnCall.Args, OpCallMethod, fnCall.Name)
+	case *ast.ReturnStatement:
+		c.compileReturn(node)
+	case *ast.BreakStatement:
+		loop := c.unwindToLoop()
+		loop.breakJumps = append(loop.breakJumps, c.emit(OpJump, 0xFFFF))
+	case *ast.ContinueStatement:
+		loop := c.unwindToLoop()
+		loop.continueJumps = append(loop.continueJumps, c.emit(OpJump, 0xFFFF))
+	case *ast.ThrowStatement:
+		c.compile(node.Value)
+		c.emit(OpThrow)
+	case *ast.TryStatement:
+		c.compileTry(node)
+	case *ast.ImportStatement:
+		c.emit(OpImport, c.stringConstant(node.Path))
+		c.emit(OpDefineVar, c.scope.declare(node.Alias))
+	default:
+		c.emit(OpNull)
+	}
+}
+
+var infixOpcodes = map[string]Opcode{
+	"+":  OpAdd,
+	"-":  OpSub,
+	"*":  OpMul,
+	"/":  OpDiv,
+	"==": OpEqual,
+	"!=": OpNotEqual,
+	"<":  OpLess,
+	"<=": OpLessEqual,
+	">":  OpGreater,
+	">=": OpGreaterEqual,
+}
+
+var prefixOpcodes = map[string]Opcode{
+	"-": OpMinus,
+	"+": OpPlus,
+	"!": OpNot,
+}
+
+func (c *Compiler) compileInfix(node *ast.InfixExpression) {
+	c.compile(node.Left)
+
+	// && and || only evaluate the right side when it's needed
+	if node.Op == "&&" || node.Op == "||" {
+		logicalOp := 0
+		if node.Op == "||" {
+			logicalOp = 1
+		}
+
+		jump := c.emit(OpLogicalLeft, logicalOp, 0xFFFF)
+		c.compile(node.Right)
+		c.emit(OpLogicalRight, logicalOp)
+		c.replaceOperands(jump, logicalOp, len(c.fn.instructions))
+
+		return
+	}
+
+	c.compile(node.Right)
+	c.emit(infixOpcodes[node.Op])
+}
+
+func (c *Compiler) compileAssignment(node *ast.AssignStatement) {
+	assignOp := 0
+	for i := range assignOps {
+		if assignOps[i] == node.AssignOp {
+			assignOp = i
+		}
+	}
+
+	switch target := node.Target.(type) {
+	case *ast.IdentifierExpression:
+		// the variable must already exist, and its current value is used for operators like +=
+		depth, slot := c.resolve(target.Value)
+		c.emit(OpGetAssignTarget, depth, slot)
+		c.compile(node.Value)
+		c.emit(OpSetVar, assignOp, depth, slot)
+	case *ast.ArrayIndexExpression:
+		targetStr := c.stringConstant(target.ToString())
+		c.compile(target.Arr)
+		c.compile(target.Index)
+		c.emit(OpCheckIndexTarget, targetStr)
+		c.compile(node.Value)
+		c.emit(OpSetIndex, assignOp, targetStr)
+	case *ast.ObjectFunctionExpression:
+		member := c.stringConstant(target.Function.(*ast.IdentifierExpression).Value)
+		c.compile(target.Object)
+		c.emit(OpCheckMemberTarget, member)
+		c.compile(node.Value)
+		c.emit(OpSetMember, assignOp, member, c.stringConstant(target.ToString()))
+	default:
+		c.emit(OpConstant, c.addConstant(object.NewError(object.TYPE_ERROR, "cannot assign to %s", node.Target.ToString())))
+		c.emit(OpThrow)
+	}
+}
+
+func (c *Compiler) compileIf(node *ast.IfStatement) {
+	c.compile(node.Condition)
+	jumpIfFalse := c.emit(OpJumpIfFalse, 0xFFFF)
+	c.compileBlock(node.Statements)
+	jump := c.emit(OpJump, 0xFFFF)
+	c.replaceOperands(jumpIfFalse, len(c.fn.instructions))
+
+	if node.Alternative != nil {
+		c.compileBlock(node.Alternative)
+	} else {
+		c.emit(OpNull)
+	}
+
+	c.replaceOperands(jump, len(c.fn.instructions))
+}
+
+func (c *Compiler) compileWhile(node *ast.WhileStatement) {
+	loop := c.pushBlock(loopBlock)
+	start := len(c.fn.instructions)
+
+	c.compile(node.Condition)
+	exit := c.emit(OpJumpIfFalse, 0xFFFF)
+	c.compileBlock(node.Statements)
+	c.emit(OpPop)
+	c.emit(OpJump, start)
+
+	c.popBlock()
+	c.replaceOperands(exit, len(c.fn.instructions))
+	c.patchLoop(loop, start, len(c.fn.instructions))
+	c.emit(OpNull)
+}
+
+func (c *Compiler) compileFor(node *ast.ForStatement) {
+	// variables declared in the loop header are only visible inside the loop
+	c.emit(OpPushScope, c.enterScope())
+	c.pushBlock(scopeBlock)
+
+	if node.Init != nil {
+		c.hoist([]ast.Statement{node.Init})
+	}
+
+	c.hoist(node.Statements)
+
+	if node.Init != nil {
+		c.compile(node.Init)
+		c.emit(OpPop)
+	}
+
+	loop := c.pushBlock(loopBlock)
+	start := len(c.fn.instructions)
+	exit := -1
+
+	if node.Condition != nil {
+		c.compile(node.Condition)
+		exit = c.emit(OpJumpIfFalse, 0xFFFF)
+	}
+
+	c.compileBlock(node.Statements)
+	c.emit(OpPop)
+	update := len(c.fn.instructions)
+
+	if node.Update != nil {
+		c.compile(node.Update)
+		c.emit(OpPop)
+	}
+
+	c.emit(OpJump, start)
+	c.popBlock()
+
+	if exit >= 0 {
+		c.replaceOperands(exit, len(c.fn.instructions))
+	}
+
+	c.patchLoop(loop, update, len(c.fn.instructions))
+	c.popBlock()
+	c.leaveScope()
+	c.emit(OpPopScope)
+	c.emit(OpNull)
+}
+
+func (c *Compiler) compileForIn(node *ast.ForInStatement) {
+	c.compile(node.Collection)
+	c.emit(OpIterStart)
+	c.pushBlock(iterBlock)
+
+	c.emit(OpPushScope, c.enterScope())
+	c.pushBlock(scopeBlock)
+	slot := c.scope.declare(node.Identifier)
+	c.hoist(node.Statements)
+
+	loop := c.pushBlock(loopBlock)
+	start := c.emit(OpIterNext, 0xFFFF)
+	c.emit(OpDefineVar, slot)
+	c.emit(OpPop)
+	c.compileBlock(node.Statements)
+	c.emit(OpPop)
+	c.emit(OpJump, start)
+	c.popBlock()
+
+	c.replaceOperands(start, len(c.fn.instructions))
+	c.patchLoop(loop, start, len(c.fn.instructions))
+	c.popBlock()
+	c.leaveScope()
+	c.emit(OpPopScope)
+	c.popBlock()
+	c.emit(OpPop)
+	c.emit(OpNull)
+}
+
+// if the try block raises an error, the VM jumps to the catch block with the error as a map on the stack
+func (c *Compiler) compileTry(node *ast.TryStatement) {
+	try := c.emit(OpTry, 0xFFFF)
+	c.pushBlock(tryBlock)
+	c.compileBlock(node.Statements)
+	c.popBlock()
+	c.emit(OpEndTry)
+	jump := c.emit(OpJump, 0xFFFF)
+
+	// the caught error is only visible in the catch block
+	c.replaceOperands(try, len(c.fn.instructions))
+	c.emit(OpPushScope, c.enterScope())
+	c.pushBlock(scopeBlock)
+
+	if node.CatchIdentifier != "" {
+		c.emit(OpDefineVar, c.scope.declare(node.CatchIdentifier))
+	}
+
+	c.emit(OpPop)
+	c.hoist(node.CatchStatements)
+	c.compileBlock(node.CatchStatements)
+	c.popBlock()
+	c.leaveScope()
+	c.emit(OpPopScope)
+
+	c.replaceOperands(jump, len(c.fn.instructions))
+}
+
+func (c *Compiler) compileFunction(name string, args []string, stmts []ast.Statement) {
+	c.fn = &function{positions: map[int]token.Position{}, parent: c.fn}
+	scopeIdx := c.enterScope()
+
+	// arguments are always given their own slots, if an argument name is repeated the last one wins
+	for _, arg := range args {
+		c.scope.declareArg(arg)
+	}
+
+	c.hoist(stmts)
+
+	// functions return the value of their last statement if there is no return statement
+	c.compileBlock(stmts)
+	c.emit(OpReturn)
+	c.leaveScope()
+
+	fn := &CompiledFunction{
+		Name:         name,
+		Instructions: c.fn.instructions,
+		Positions:    c.fn.positions,
+		NumArgs:      len(args),
+		Scope:        scopeIdx,
+		Bytecode:     c.bytecode,
+	}
+
+	c.fn = c.fn.parent
+	c.emit(OpClosure, c.addConstant(fn))
+}
+
+// the function being called is already on the stack
+func (c *Compiler) compileCall(args []ast.Expression, op Opcode, name string) {
+	for _, arg := range args {
+		c.compile(arg)
+	}
+
+	c.emit(op, len(args), c.stringConstant(name))
+}
+
+func (c *Compiler) compileReturn(node *ast.ReturnStatement) {
+	c.compile(node.ReturnVal)
+
+	if !c.fn.main {
+		c.emit(OpReturn)
+		return
+	}
+
+	// at the top level, a return only ends the statement it's in
+	c.emit(OpPop)
+
+	for i := len(c.fn.blocks) - 1; i >= 0; i-- {
+		c.unwind(c.fn.blocks[i])
+	}
+
+	c.emit(OpNull)
+	c.fn.returnJumps = append(c.fn.returnJumps, c.emit(OpJump, 0xFFFF))
+}
+
+// leave any try blocks and scopes inside of the innermost loop before jumping to the start or end of it
+func (c *Compiler) unwindToLoop() *block {
+	for i := len(c.fn.blocks) - 1; i >= 0; i-- {
+		if c.fn.blocks[i].kind == loopBlock {
+			return c.fn.blocks[i]
+		}
+
+		c.unwind(c.fn.blocks[i])
+	}
+
+	// the parser only allows break and continue inside of a loop
+	return &block{kind: loopBlock}
+}
+
+func (c *Compiler) unwind(b *block) {
+	switch b.kind {
+	case tryBlock:
+		c.emit(OpEndTry)
+	case scopeBlock:
+		c.emit(OpPopScope)
+	case iterBlock:
+		c.emit(OpPop)
+	}
+}
+
+func (c *Compiler) pushBlock(kind blockKind) *block {
+	b := &block{kind: kind}
+	c.fn.blocks = append(c.fn.blocks, b)
+
+	return b
+}
+
+func (c *Compiler) popBlock() {
+	c.fn.blocks = c.fn.blocks[:len(c.fn.blocks)-1]
+}
+
+func (c *Compiler) patchLoop(loop *block, continueAddr int, breakAddr int) {
+	for _, jump := range loop.continueJumps {
+		c.replaceOperands(jump, continueAddr)
+	}
+
+	for _, jump := range loop.breakJumps {
+		c.replaceOperands(jump, breakAddr)
+	}
+}
+
+// compile the statements of a block, leaving the value of the last statement on the stack
+func (c *Compiler) compileBlock(stmts []ast.Statement) {
+	if len(stmts) == 0 {
+		c.emit(OpNull)
+		return
+	}
+
+	for i, stmt := range stmts {
+		if i > 0 {
+			c.emit(OpPop)
+		}
+
+		c.compile(stmt)
+	}
+}
+
+// declare the variables a block defines in the current scope before compiling it, so functions can refer to
+// variables that are defined after them. Blocks of if statements, while loops and try blocks share the
+// scope they're in, the other blocks are hoisted when their own scope is created.
+func (c *Compiler) hoist(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.VarStatement:
+			c.scope.declare(stmt.Identifier)
+		case *ast.FunctionDef:
+			c.scope.declare(stmt.Name)
+		case *ast.ImportStatement:
+			c.scope.declare(stmt.Alias)
+		case *ast.IfStatement:
+			c.hoist(stmt.Statements)
+			c.hoist(stmt.Alternative)
+		case *ast.WhileStatement:
+			c.hoist(stmt.Statements)
+		case *ast.TryStatement:
+			c.hoist(stmt.Statements)
+		}
+	}
+}
+
+func (c *Compiler) enterScope() int {
+	info := &Scope{}
+	c.bytecode.Scopes = append(c.bytecode.Scopes, info)
+	c.scope = &scope{index: len(c.bytecode.Scopes) - 1, info: info, slots: map[string]int{}, parent: c.scope}
+
+	return c.scope.index
+}
+
+func (c *Compiler) leaveScope() {
+	c.scope = c.scope.parent
+}
+
+// find how many scopes up a variable is and its slot in that scope. Names that aren't declared anywhere
+// are given a slot in the top-level scope that is never set, so using them raises an error at runtime.
+func (c *Compiler) resolve(name string) (int, int) {
+	depth := 0
+	s := c.scope
+
+	for s.parent != nil {
+		if slot, ok := s.slots[name]; ok {
+			return depth, slot
+		}
+
+		s = s.parent
+		depth++
+	}
+
+	return depth, s.declare(name)
+}
+
+func (s *scope) declare(name string) int {
+	if slot, ok := s.slots[name]; ok {
+		return slot
+	}
+
+	return s.declareArg(name)
+}
+
+func (s *scope) declareArg(name string) int {
+	slot := len(s.info.Names)
+	s.info.Names = append(s.info.Names, name)
+	s.slots[name] = slot
+
+	return slot
+}
+
+func (c *Compiler) emit(op Opcode, operands ...int) int {
+	c.checkOperands(op, operands, c.pos)
+	pos := len(c.fn.instructions)
+	c.fn.instructions = append(c.fn.instructions, Make(op, operands...)...)
+
+	if c.pos.IsValid() {
+		c.fn.positions[pos] = c.pos
+	}
+
+	return pos
+}
+
+// change the operands of an instruction that has already been emitted, used to fill in jump addresses
+func (c *Compiler) replaceOperands(pos int, operands ...int) {
+	c.checkOperands(Opcode(c.fn.instructions[pos]), operands, c.fn.positions[pos])
+	ins := Make(Opcode(c.fn.instructions[pos]), operands...)
+	copy(c.fn.instructions[pos:], ins)
+}
+
+// operands have a fixed width, Make would silently truncate values that don't fit so they are checked first
+func (c *Compiler) checkOperands(op Opcode, operands []int, pos token.Position) {
+	if c.err != nil {
+		return
+	}
+
+	def := definitions[op]
+
+	for i, operand := range operands {
+		limit := 1<<(8*def.OperandWidths[i]) - 1
+
+		if operand < 0 || operand > limit {
+			c.err = &CompileError{Pos: pos, Message: fmt.Sprintf("%s exceeds the limit of %d", operandLimit(op, i), limit)}
+			return
+		}
+	}
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.bytecode.Constants = append(c.bytecode.Constants, obj)
+	return len(c.bytecode.Constants) - 1
+}
+
+// literals are only added to the constant pool once
+func (c *Compiler) constant(typ string, value interface{}, obj object.Object) int {
+	key := constantKey{typ, value}
+	if idx, ok := c.constants[key]; ok {
+		return idx
+	}
+
+	idx := c.addConstant(obj)
+	c.constants[key] = idx
+
+	return idx
+}
+
+func (c *Compiler) stringConstant(value string) int {
+	return c.constant(object.STRING_OBJ, value, &object.StringObject{Value: value})
+}
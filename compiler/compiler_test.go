@@ -0,0 +1,188 @@
+package compiler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MarkyMan4/yetti/lexer"
+	"github.com/MarkyMan4/yetti/object"
+	"github.com/MarkyMan4/yetti/parser"
+)
+
+func compileProgram(t *testing.T, input string) *Bytecode {
+	p := parser.NewParser(lexer.NewLexer(input))
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("failed to parse program: %v\n", p.Errors)
+	}
+
+	bytecode, err := NewCompiler("").Compile(prog)
+	if err != nil {
+		t.Fatalf("failed to compile program: %v\n", err)
+	}
+
+	return bytecode
+}
+
+func expectInstructions(t *testing.T, ins Instructions, expected string) {
+	if ins.String() != expected {
+		t.Errorf("expected instructions\n%s\ngot\n%s\n", expected, ins.String())
+	}
+}
+
+func TestMakeAndReadOperands(t *testing.T) {
+	ins := Make(OpSetVar, 2, 1, 65534)
+	expected := []byte{byte(OpSetVar), 2, 1, 255, 254}
+
+	if string(ins) != string(expected) {
+		t.Fatalf("expected %v, got %v\n", expected, ins)
+	}
+
+	def, err := Lookup(ins[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operands, read := ReadOperands(def, ins[1:])
+	if read != 4 || operands[0] != 2 || operands[1] != 1 || operands[2] != 65534 {
+		t.Errorf("expected operands [2 1 65534] reading 4 bytes, got %v reading %d\n", operands, read)
+	}
+}
+
+func TestCompileWhileLoop(t *testing.T) {
+	bytecode := compileProgram(t, `
+		var x = 1;
+		while (x < 10) {
+			x += 2;
+		}
+	`)
+
+	expectInstructions(t, bytecode.Main.Instructions, `0000 OpConstant 0
+0003 OpDefineVar 0
+0006 OpPop
+0007 OpGetVar 0 0
+0011 OpConstant 1
+0014 OpLess
+0015 OpJumpIfFalse 34
+0018 OpGetAssignTarget 0 0
+0022 OpConstant 2
+0025 OpSetVar 1 0 0
+0030 OpPop
+0031 OpJump 7
+0034 OpNull
+0035 OpPop
+0036 OpNull
+0037 OpReturn
+`)
+}
+
+func TestCompileFunction(t *testing.T) {
+	bytecode := compileProgram(t, `
+		var y = 1;
+
+		fun add(a, b) {
+			return a + b + y;
+		}
+	`)
+
+	fn, ok := bytecode.Constants[len(bytecode.Constants)-1].(*CompiledFunction)
+	if !ok {
+		t.Fatal("expected the last constant to be the compiled function")
+	}
+
+	if fn.NumArgs != 2 || len(bytecode.Scopes[fn.Scope].Names) != 2 {
+		t.Errorf("expected 2 arguments in a scope with 2 slots, got %d arguments and slots %v\n", fn.NumArgs, bytecode.Scopes[fn.Scope].Names)
+	}
+
+	// y is a variable of the scope one level up
+	expectInstructions(t, fn.Instructions, `0000 OpGetVar 0 0
+0004 OpGetVar 0 1
+0008 OpAdd
+0009 OpGetVar 1 0
+0013 OpAdd
+0014 OpReturn
+0015 OpReturn
+`)
+}
+
+func TestConstantsAreShared(t *testing.T) {
+	bytecode := compileProgram(t, `
+		var a = 1;
+		var b = 1;
+		var c = "x" + "x";
+	`)
+
+	if len(bytecode.Constants) != 2 {
+		t.Fatalf("expected 2 constants, got %d\n", len(bytecode.Constants))
+	}
+
+	if bytecode.Constants[1].(*object.StringObject).Value != "x" {
+		t.Errorf("expected the second constant to be x, got %s\n", bytecode.Constants[1].ToString())
+	}
+}
+
+func TestHoisting(t *testing.T) {
+	bytecode := compileProgram(t, `
+		fun f() {
+			return later;
+		}
+
+		if (true) {
+			var later = 1;
+		}
+
+		try {
+			var inTry = 2;
+		} catch(e) {
+			var inCatch = 3;
+		}
+
+		print(undefined);
+	`)
+
+	// variables in catch blocks get their own scope, names that are never declared are added at the end
+	expected := []string{"f", "later", "inTry", "print", "undefined"}
+	names := bytecode.Scopes[bytecode.Main.Scope].Names
+
+	if len(names) != len(expected) {
+		t.Fatalf("expected top-level names %v, got %v\n", expected, names)
+	}
+
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("expected top-level names %v, got %v\n", expected, names)
+		}
+	}
+}
+
+func TestOperandLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// the jump back to the start of the loop is further than an address can encode
+		{"var x = 0;\nwhile (x < 2) {\n" + strings.Repeat("x += 1;\n", 20000) + "}", "2:1: size of the compiled function in bytes exceeds the limit of 65535"},
+		{"print(" + strings.Repeat("1, ", 300) + "1);", "1:1: number of arguments in a function call exceeds the limit of 255"},
+	}
+
+	for _, tt := range tests {
+		p := parser.NewParser(lexer.NewLexer(tt.input))
+		prog := p.Parse()
+
+		if len(p.Errors) > 0 {
+			t.Fatalf("failed to parse program: %v\n", p.Errors)
+		}
+
+		_, err := NewCompiler("").Compile(prog)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("expected compile error %q, got %v\n", tt.expected, err)
+		}
+
+		var compileErr *CompileError
+		if !errors.As(err, &compileErr) || !compileErr.Pos.IsValid() {
+			t.Errorf("expected a CompileError with a position, got %#v\n", err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package yetti
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/MarkyMan4/yetti/object"
+)
+
+// convert a Go value to an object. Supports nil, booleans, numbers, strings, slices and arrays, maps with
+// string keys, and values that are already objects.
+func ToObject(val interface{}) (object.Object, error) {
+	switch val := val.(type) {
+	case nil:
+		return &object.NullObject{}, nil
+	case object.Object:
+		return val, nil
+	}
+
+	// reflection is used so named types like time.Duration are converted by their underlying type
+	rv := reflect.ValueOf(val)
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return &object.BooleanObject{Value: rv.Bool()}, nil
+	case reflect.String:
+		return &object.StringObject{Value: rv.String()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.IntegerObject{Value: rv.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		// integers are signed 64 bit, larger values would wrap around to negative numbers
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("cannot convert %d to an integer, it is larger than %d", rv.Uint(), int64(math.MaxInt64))
+		}
+
+		return &object.IntegerObject{Value: int64(rv.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &object.FloatObject{Value: rv.Float()}, nil
+	case reflect.Slice, reflect.Array:
+		arr := &object.ArrayObject{Items: []object.Object{}}
+
+		for i := 0; i < rv.Len(); i++ {
+			item, err := ToObject(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			arr.Items = append(arr.Items, item)
+		}
+
+		return arr, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot convert map with keys of type %s, keys must be strings", rv.Type().Key())
+		}
+
+		// Go maps aren't ordered, so keys are sorted to give the same map every time
+		keys := make([]string, 0, rv.Len())
+		for _, key := range rv.MapKeys() {
+			keys = append(keys, key.String())
+		}
+
+		sort.Strings(keys)
+		mapObj := object.NewMapObject()
+
+		for _, key := range keys {
+			item, err := ToObject(rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())).Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			mapObj.Set(&object.StringObject{Value: key}, item)
+		}
+
+		return mapObj, nil
+	}
+
+	return nil, fmt.Errorf("cannot convert value of type %T to an object", val)
+}
+
+// convert an object to a Go value. null becomes nil, integers become int64, floats become float64,
+// arrays become []interface{} and maps become map[string]interface{}. Maps must only have string keys,
+// and other objects such as functions can't be converted.
+func FromObject(obj object.Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *object.NullObject:
+		return nil, nil
+	case *object.BooleanObject:
+		return obj.Value, nil
+	case *object.IntegerObject:
+		return obj.Value, nil
+	case *object.FloatObject:
+		return obj.Value, nil
+	case *object.StringObject:
+		return obj.Value, nil
+	case *object.ArrayObject:
+		items := make([]interface{}, len(obj.Items))
+
+		for i := range obj.Items {
+			item, err := FromObject(obj.Items[i])
+			if err != nil {
+				return nil, err
+			}
+
+			items[i] = item
+		}
+
+		return items, nil
+	case *object.MapObject:
+		res := make(map[string]interface{}, len(obj.Keys))
+
+		for _, hashKey := range obj.Keys {
+			pair := obj.Pairs[hashKey]
+
+			key, ok := pair.Key.(*object.StringObject)
+			if !ok {
+				return nil, fmt.Errorf("cannot convert map with key of type %s, keys must be strings", pair.Key.Type())
+			}
+
+			val, err := FromObject(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			res[key.Value] = val
+		}
+
+		return res, nil
+	}
+
+	return nil, fmt.Errorf("cannot convert object of type %s to a Go value", obj.Type())
+}
@@ -0,0 +1,60 @@
+package diagnostics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MarkyMan4/yetti/token"
+)
+
+// format an error message with its location, followed by the line of source it happened on
+// and a caret under the column, e.g.
+//
+//	main.yti:3:9: NameError: identifier y is not defined
+//	var x = y + 1;
+//	        ^
+//
+// the source line is left out if the position isn't in src, such as when the source isn't available
+func Format(file string, src string, pos token.Position, msg string) string {
+	if !pos.IsValid() {
+		if file == "" {
+			return msg
+		}
+
+		return fmt.Sprintf("%s: %s", file, msg)
+	}
+
+	location := pos.String()
+	if file != "" {
+		location = file + ":" + location
+	}
+
+	res := fmt.Sprintf("%s: %s", location, msg)
+
+	lines := strings.Split(src, "\n")
+	if src == "" || pos.Line > len(lines) {
+		return res
+	}
+
+	line := []rune(strings.TrimRight(lines[pos.Line-1], "\r"))
+
+	return res + "\n" + string(line) + "\n" + caret(line, pos.Col)
+}
+
+// build the line with a caret under the given column
+// tabs are kept so the caret lines up with the source however wide tabs are displayed
+func caret(line []rune, col int) string {
+	var sb strings.Builder
+
+	for i := 0; i < col-1; i++ {
+		if i < len(line) && line[i] == '\t' {
+			sb.WriteRune('\t')
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+
+	sb.WriteRune('^')
+
+	return sb.String()
+}
@@ -0,0 +1,35 @@
+package diagnostics
+
+import (
+	"testing"
+
+	"github.com/MarkyMan4/yetti/token"
+)
+
+func TestFormat(t *testing.T) {
+	src := "var x = 1;\n\tvar y = z;\n"
+
+	tests := []struct {
+		file     string
+		pos      token.Position
+		expected string
+	}{
+		{"main.yti", token.Position{Line: 2, Col: 10}, "main.yti:2:10: oops\n\tvar y = z;\n\t        ^"},
+		{"", token.Position{Line: 1, Col: 1}, "1:1: oops\nvar x = 1;\n^"},
+		{"main.yti", token.Position{}, "main.yti: oops"},
+		{"main.yti", token.Position{Line: 10, Col: 1}, "main.yti:10:1: oops"},
+	}
+
+	for _, tt := range tests {
+		res := Format(tt.file, src, tt.pos, "oops")
+
+		if res != tt.expected {
+			t.Fatalf("expected\n%s\nbut got\n%s\n", tt.expected, res)
+		}
+	}
+
+	// without the source there's no line to show
+	if res := Format("main.yti", "", token.Position{Line: 1, Col: 5}, "oops"); res != "main.yti:1:5: oops" {
+		t.Fatalf("expected only the location and message but got\n%s\n", res)
+	}
+}
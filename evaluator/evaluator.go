@@ -1,8 +1,7 @@
 package evaluator
 
 import (
-	"fmt"
-	"os"
+	"strings"
 
 	"github.com/MarkyMan4/yetti/ast"
 	"github.com/MarkyMan4/yetti/object"
@@ -10,6 +9,18 @@ import (
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	res := evalNode(node, env)
+
+	// errors are tagged with the position of the innermost node they were raised from
+	if errObj, ok := res.(*object.ErrorObject); ok && !errObj.Pos.IsValid() && node != nil && node.Position().IsValid() {
+		errObj.File = env.File()
+		errObj.Pos = node.Position()
+	}
+
+	return res
+}
+
+func evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.IntegerLiteral:
 		return &object.IntegerObject{Value: node.Value}
@@ -17,78 +28,104 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.FloatObject{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.StringObject{Value: node.Value}
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
 	case *ast.BooleanLiteral:
 		return &object.BooleanObject{Value: node.Value}
+	case *ast.NullLiteral:
+		return &object.NullObject{}
 	case *ast.ArrayExpression:
 		return evalArrayExpression(node.Items, env)
+	case *ast.MapExpression:
+		return evalMapExpression(node, env)
 	case *ast.ArrayIndexExpression:
 		return evalArrayIndexExpression(node, env)
 	case *ast.IdentifierExpression:
 		obj, ok := env.Get(node.Value)
 
 		if !ok {
-			fmt.Printf("identifier %s is not defined\n", node.Value)
-			os.Exit(1)
+			return object.NewError(object.NAME_ERROR, "identifier %s is not defined", node.Value)
 		}
 
 		return obj
+	case *ast.PrefixExpression:
+		right := Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+
+		return evalPrefixExpression(node.Op, right)
 	case *ast.InfixExpression:
+		// && and || only evaluate the right side when it's needed
+		if node.Op == "&&" || node.Op == "||" {
+			return evalLogicalExpression(node, env)
+		}
+
 		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+
 		right := Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+
 		return evalInfixExpression(node.Op, left, right)
 	case *ast.VarStatement:
 		val := Eval(node.Value, env)
-		env.Set(node.Identifier, val, true)
-		return val
-	case *ast.AssignStatement:
-		obj, ok := env.Get(node.Identifier)
-
-		if !ok {
-			fmt.Printf("variable %s has not been declared\n", node.Identifier)
-			os.Exit(1)
+		if isError(val) {
+			return val
 		}
 
-		left := obj
-		right := Eval(node.Value, env)
-		val := evalAssignStatement(node.AssignOp, left, right)
-		env.Set(node.Identifier, val, false)
-
+		env.Set(node.Identifier, val, true)
 		return val
+	case *ast.AssignStatement:
+		return evalAssignStatement(node, env)
 	case *ast.IfStatement:
-		condResult := Eval(node.Condition, env)
-		if condResult.Type() != object.BOOLEAN_OBJ {
-			fmt.Println("condition must return a boolean")
-			os.Exit(1)
+		condResult := evalCondition(node.Condition, env)
+		if isError(condResult) {
+			return condResult
 		}
 
-		// if the condition is still true, run all statements and evaluate the loop again
 		if condResult.(*object.BooleanObject).Value {
 			return evalStatements(node.Statements, env)
+		} else if node.Alternative != nil {
+			return evalStatements(node.Alternative, env)
 		}
 
 		return &object.NullObject{}
 	case *ast.WhileStatement:
-		condResult := Eval(node.Condition, env)
-		if condResult.Type() != object.BOOLEAN_OBJ {
-			fmt.Println("condition must return a boolean")
-			os.Exit(1)
-		}
-
-		// if the condition is still true, run all statements and evaluate the loop again
-		if condResult.(*object.BooleanObject).Value {
-			for i := range node.Statements {
-				Eval(node.Statements[i], env)
-			}
-
-			Eval(node, env)
-		}
+		return evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+	case *ast.ForInStatement:
+		return evalForInStatement(node, env)
 	case *ast.FunctionDef:
-		env.Set(node.Name, &object.FunctionObject{Args: node.Args, Statements: node.Statements}, true)
+		env.Set(node.Name, &object.FunctionObject{Args: node.Args, Statements: node.Statements, Env: env, File: env.File()}, true)
+	case *ast.FunctionLiteral:
+		return &object.FunctionObject{Args: node.Args, Statements: node.Statements, Env: env, File: env.File()}
 	case *ast.FunctionCall:
 		return evalFunctionCall(node, env)
+	case *ast.CallExpression:
+		return evalCallExpression(node, env)
 	case *ast.ReturnStatement:
 		res := Eval(node.ReturnVal, env)
+		if isError(res) {
+			return res
+		}
+
 		return &object.ReturnObject{Value: res}
+	case *ast.BreakStatement:
+		return &object.BreakObject{}
+	case *ast.ContinueStatement:
+		return &object.ContinueObject{}
+	case *ast.ThrowStatement:
+		return evalThrowStatement(node, env)
+	case *ast.TryStatement:
+		return evalTryStatement(node, env)
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
 	case *ast.ObjectFunctionExpression:
 		return evalObjFunCall(node, env)
 	}
@@ -96,7 +133,234 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	return nil
 }
 
-func evalAssignStatement(assignOp string, left object.Object, right object.Object) object.Object {
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ERROR_OBJ
+}
+
+// evaluate the condition of an if statement or loop, returns an error if the condition isn't a boolean
+func evalCondition(condition ast.Expression, env *object.Environment) object.Object {
+	condResult := Eval(condition, env)
+	if isError(condResult) {
+		return condResult
+	}
+
+	if err := CheckCondition(condResult); err != nil {
+		return err
+	}
+
+	return condResult
+}
+
+// the loop runs iteratively so long running loops don't grow the stack
+func evalWhileStatement(whileStmt *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condResult := evalCondition(whileStmt.Condition, env)
+		if isError(condResult) {
+			return condResult
+		}
+
+		if !condResult.(*object.BooleanObject).Value {
+			break
+		}
+
+		res := evalStatements(whileStmt.Statements, env)
+		if res.Type() == object.BREAK_OBJ {
+			break
+		}
+
+		if res.Type() == object.RETURN_OBJ || res.Type() == object.ERROR_OBJ {
+			return res
+		}
+	}
+
+	return &object.NullObject{}
+}
+
+func evalForStatement(forStmt *ast.ForStatement, env *object.Environment) object.Object {
+	// variables declared in the loop header are only visible inside the loop
+	loopEnv := object.CreateChildEnvironment(env)
+
+	if forStmt.Init != nil {
+		if res := Eval(forStmt.Init, loopEnv); isError(res) {
+			return res
+		}
+	}
+
+	for {
+		if forStmt.Condition != nil {
+			condResult := evalCondition(forStmt.Condition, loopEnv)
+			if isError(condResult) {
+				return condResult
+			}
+
+			if !condResult.(*object.BooleanObject).Value {
+				break
+			}
+		}
+
+		res := evalStatements(forStmt.Statements, loopEnv)
+		if res.Type() == object.BREAK_OBJ {
+			break
+		}
+
+		if res.Type() == object.RETURN_OBJ || res.Type() == object.ERROR_OBJ {
+			return res
+		}
+
+		if forStmt.Update != nil {
+			if res := Eval(forStmt.Update, loopEnv); isError(res) {
+				return res
+			}
+		}
+	}
+
+	return &object.NullObject{}
+}
+
+func evalForInStatement(forIn *ast.ForInStatement, env *object.Environment) object.Object {
+	collection := Eval(forIn.Collection, env)
+	if isError(collection) {
+		return collection
+	}
+
+	iter, err := Iterate(collection)
+	if err != nil {
+		return err
+	}
+
+	loopEnv := object.CreateChildEnvironment(env)
+
+	for {
+		item, ok, err := iter.Next()
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			break
+		}
+
+		loopEnv.Set(forIn.Identifier, item, true)
+
+		res := evalStatements(forIn.Statements, loopEnv)
+		if res.Type() == object.BREAK_OBJ {
+			break
+		}
+
+		if res.Type() == object.RETURN_OBJ || res.Type() == object.ERROR_OBJ {
+			return res
+		}
+	}
+
+	return &object.NullObject{}
+}
+
+// throwing a string raises an Error with that message, a map can be thrown to set the kind of error,
+// e.g. throw {"kind": "ValueError", "message": "n must be positive"};
+func evalThrowStatement(throwStmt *ast.ThrowStatement, env *object.Environment) object.Object {
+	val := Eval(throwStmt.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	return ThrowValue(val)
+}
+
+// if the try block raises an error, the catch block is run with the error as a map with the keys kind and message
+func evalTryStatement(tryStmt *ast.TryStatement, env *object.Environment) object.Object {
+	res := evalStatements(tryStmt.Statements, env)
+
+	errObj, ok := res.(*object.ErrorObject)
+	if !ok || errObj.Exit {
+		return res
+	}
+
+	// the caught error is only visible in the catch block
+	catchEnv := object.CreateChildEnvironment(env)
+
+	if tryStmt.CatchIdentifier != "" {
+		catchEnv.Set(tryStmt.CatchIdentifier, CaughtError(errObj), true)
+	}
+
+	return evalStatements(tryStmt.CatchStatements, catchEnv)
+}
+
+func evalAssignStatement(assignStmt *ast.AssignStatement, env *object.Environment) object.Object {
+	switch target := assignStmt.Target.(type) {
+	case *ast.IdentifierExpression:
+		left, ok := env.Get(target.Value)
+
+		if !ok {
+			return object.NewError(object.NAME_ERROR, "variable %s has not been declared", target.Value)
+		}
+
+		right := Eval(assignStmt.Value, env)
+		if isError(right) {
+			return right
+		}
+
+		val := evalAssignOp(assignStmt.AssignOp, left, right)
+		if isError(val) {
+			return val
+		}
+
+		env.Set(target.Value, val, false)
+
+		return val
+	case *ast.ArrayIndexExpression:
+		return evalIndexAssignment(target, assignStmt, env)
+	case *ast.ObjectFunctionExpression:
+		return evalMemberAssignment(target, assignStmt, env)
+	default:
+		return object.NewError(object.TYPE_ERROR, "cannot assign to %s", assignStmt.Target.ToString())
+	}
+}
+
+// assign to an array index or map key, e.g. xs[0] = 1; or m["a"] += 1;
+func evalIndexAssignment(target *ast.ArrayIndexExpression, assignStmt *ast.AssignStatement, env *object.Environment) object.Object {
+	obj := Eval(target.Arr, env)
+	if isError(obj) {
+		return obj
+	}
+
+	idxObj := Eval(target.Index, env)
+	if isError(idxObj) {
+		return idxObj
+	}
+
+	if err := CheckIndexTarget(obj, idxObj, target.ToString()); err != nil {
+		return err
+	}
+
+	right := Eval(assignStmt.Value, env)
+	if isError(right) {
+		return right
+	}
+
+	return AssignIndex(obj, idxObj, assignStmt.AssignOp, right, target.ToString())
+}
+
+// assign to a member of a map, e.g. config.port = 80;
+func evalMemberAssignment(target *ast.ObjectFunctionExpression, assignStmt *ast.AssignStatement, env *object.Environment) object.Object {
+	member := target.Function.(*ast.IdentifierExpression)
+	obj := Eval(target.Object, env)
+	if isError(obj) {
+		return obj
+	}
+
+	if err := CheckMemberTarget(obj, member.Value); err != nil {
+		return err
+	}
+
+	right := Eval(assignStmt.Value, env)
+	if isError(right) {
+		return right
+	}
+
+	return AssignIndex(obj, &object.StringObject{Value: member.Value}, assignStmt.AssignOp, right, target.ToString())
+}
+
+func evalAssignOp(assignOp string, left object.Object, right object.Object) object.Object {
 	switch assignOp {
 	case "+=":
 		return evalInfixExpression("+", left, right)
@@ -110,11 +374,8 @@ func evalAssignStatement(assignOp string, left object.Object, right object.Objec
 		// default is a normal assignment, so just return the right hand side
 		return right
 	default:
-		fmt.Printf("unknown operator %s\n", assignOp)
-		os.Exit(1)
+		return object.NewError(object.TYPE_ERROR, "unknown operator %s", assignOp)
 	}
-
-	return nil
 }
 
 func evalInfixExpression(op string, left object.Object, right object.Object) object.Object {
@@ -128,9 +389,105 @@ func evalInfixExpression(op string, left object.Object, right object.Object) obj
 		return evalFloatInfixExpression(op, left, right)
 	} else if left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ {
 		return evalStringInfixExpression(op, left, right)
+	} else if left.Type() == object.BOOLEAN_OBJ && right.Type() == object.BOOLEAN_OBJ {
+		return evalBooleanInfixExpression(op, left, right)
 	}
 
-	return nil
+	// any two objects can be compared for equality
+	switch op {
+	case "==":
+		return &object.BooleanObject{Value: objectsEqual(left, right)}
+	case "!=":
+		return &object.BooleanObject{Value: !objectsEqual(left, right)}
+	default:
+		return object.NewError(object.TYPE_ERROR, "unsupported operator '%s' for types %s, %s", op, left.Type(), right.Type())
+	}
+}
+
+// equality for objects that aren't numbers, strings or booleans
+// objects of different types are never equal, otherwise objects are only equal if they are the same object
+func objectsEqual(left object.Object, right object.Object) bool {
+	if left.Type() != right.Type() {
+		return false
+	}
+
+	if left.Type() == object.NULL_OBJ {
+		return true
+	}
+
+	return left == right
+}
+
+func evalPrefixExpression(op string, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.IntegerObject:
+		switch op {
+		case "-":
+			return &object.IntegerObject{Value: -right.Value}
+		case "+":
+			return &object.IntegerObject{Value: right.Value}
+		}
+	case *object.FloatObject:
+		switch op {
+		case "-":
+			return &object.FloatObject{Value: -right.Value}
+		case "+":
+			return &object.FloatObject{Value: right.Value}
+		}
+	case *object.BooleanObject:
+		if op == "!" {
+			return &object.BooleanObject{Value: !right.Value}
+		}
+	}
+
+	return object.NewError(object.TYPE_ERROR, "unsupported operator '%s' for type %s", op, right.Type())
+}
+
+// evaluate && and ||, the right side is only evaluated if the left side doesn't determine the result
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	leftObj := Eval(node.Left, env)
+	if isError(leftObj) {
+		return leftObj
+	}
+
+	left, err := LogicalOperand(node.Op, "left", leftObj)
+	if err != nil {
+		return err
+	}
+
+	if node.Op == "&&" && !left {
+		return &object.BooleanObject{Value: false}
+	}
+
+	if node.Op == "||" && left {
+		return &object.BooleanObject{Value: true}
+	}
+
+	rightObj := Eval(node.Right, env)
+	if isError(rightObj) {
+		return rightObj
+	}
+
+	right, err := LogicalOperand(node.Op, "right", rightObj)
+	if err != nil {
+		return err
+	}
+
+	return &object.BooleanObject{Value: right}
+}
+
+func evalBooleanInfixExpression(op string, left object.Object, right object.Object) object.Object {
+	leftVal := left.(*object.BooleanObject).Value
+	rightVal := right.(*object.BooleanObject).Value
+
+	switch op {
+	case "==":
+		return &object.BooleanObject{Value: leftVal == rightVal}
+	case "!=":
+		return &object.BooleanObject{Value: leftVal != rightVal}
+	default:
+		return object.NewError(object.TYPE_ERROR, "unsupported operator '%s' for types %s, %s", op, left.Type(), right.Type())
+	}
 }
 
 func evalIntegerInfixExpression(op string, left object.Object, right object.Object) object.Object {
@@ -153,12 +510,14 @@ func evalIntegerInfixExpression(op string, left object.Object, right object.Obje
 		return &object.BooleanObject{Value: leftVal <= rightVal}
 	case "==":
 		return &object.BooleanObject{Value: leftVal == rightVal}
+	case "!=":
+		return &object.BooleanObject{Value: leftVal != rightVal}
 	case ">":
 		return &object.BooleanObject{Value: leftVal > rightVal}
 	case ">=":
 		return &object.BooleanObject{Value: leftVal >= rightVal}
 	default:
-		return &object.ErrorObject{Message: fmt.Sprintf("unsupported operator '%s' for types %s, %s", op, left.Type(), right.Type())}
+		return object.NewError(object.TYPE_ERROR, "unsupported operator '%s' for types %s, %s", op, left.Type(), right.Type())
 	}
 }
 
@@ -181,12 +540,14 @@ func evalFloatIntegerInfixExpression(op string, left object.Object, right object
 		return &object.BooleanObject{Value: leftVal <= rightVal}
 	case "==":
 		return &object.BooleanObject{Value: leftVal == rightVal}
+	case "!=":
+		return &object.BooleanObject{Value: leftVal != rightVal}
 	case ">":
 		return &object.BooleanObject{Value: leftVal > rightVal}
 	case ">=":
 		return &object.BooleanObject{Value: leftVal >= rightVal}
 	default:
-		return &object.ErrorObject{Message: fmt.Sprintf("unsupported operator '%s' for types %s, %s", op, left.Type(), right.Type())}
+		return object.NewError(object.TYPE_ERROR, "unsupported operator '%s' for types %s, %s", op, left.Type(), right.Type())
 	}
 }
 
@@ -209,12 +570,14 @@ func evalIntegerFloatInfixExpression(op string, left object.Object, right object
 		return &object.BooleanObject{Value: leftVal <= rightVal}
 	case "==":
 		return &object.BooleanObject{Value: leftVal == rightVal}
+	case "!=":
+		return &object.BooleanObject{Value: leftVal != rightVal}
 	case ">":
 		return &object.BooleanObject{Value: leftVal > rightVal}
 	case ">=":
 		return &object.BooleanObject{Value: leftVal >= rightVal}
 	default:
-		return &object.ErrorObject{Message: fmt.Sprintf("unsupported operator '%s' for types %s, %s", op, left.Type(), right.Type())}
+		return object.NewError(object.TYPE_ERROR, "unsupported operator '%s' for types %s, %s", op, left.Type(), right.Type())
 	}
 }
 
@@ -237,12 +600,14 @@ func evalFloatInfixExpression(op string, left object.Object, right object.Object
 		return &object.BooleanObject{Value: leftVal <= rightVal}
 	case "==":
 		return &object.BooleanObject{Value: leftVal == rightVal}
+	case "!=":
+		return &object.BooleanObject{Value: leftVal != rightVal}
 	case ">":
 		return &object.BooleanObject{Value: leftVal > rightVal}
 	case ">=":
 		return &object.BooleanObject{Value: leftVal >= rightVal}
 	default:
-		return &object.ErrorObject{Message: fmt.Sprintf("unsupported operator '%s' for types %s, %s", op, left.Type(), right.Type())}
+		return object.NewError(object.TYPE_ERROR, "unsupported operator '%s' for types %s, %s", op, left.Type(), right.Type())
 	}
 }
 
@@ -259,23 +624,33 @@ func evalStringInfixExpression(op string, left object.Object, right object.Objec
 		return &object.BooleanObject{Value: leftVal <= rightVal}
 	case "==":
 		return &object.BooleanObject{Value: leftVal == rightVal}
+	case "!=":
+		return &object.BooleanObject{Value: leftVal != rightVal}
 	case ">":
 		return &object.BooleanObject{Value: leftVal > rightVal}
 	case ">=":
 		return &object.BooleanObject{Value: leftVal >= rightVal}
 	default:
-		return &object.ErrorObject{Message: fmt.Sprintf("unsupported operator '%s' for types %s, %s", op, left.Type(), right.Type())}
+		return object.NewError(object.TYPE_ERROR, "unsupported operator '%s' for types %s, %s", op, left.Type(), right.Type())
 	}
 }
 
 func evalStatements(stmts []ast.Statement, env *object.Environment) object.Object {
-	var res object.Object
+	var res object.Object = &object.NullObject{}
 
 	// evaluate each statement
 	for i := range stmts {
-		res := Eval(stmts[i], env)
+		res = Eval(stmts[i], env)
 
-		if res.Type() == object.RETURN_OBJ || res.Type() == object.ERROR_OBJ {
+		// some statements such as while loops don't produce a value
+		if res == nil {
+			res = &object.NullObject{}
+			continue
+		}
+
+		// stop evaluating the block and let the caller handle returns, errors and loop control
+		switch res.Type() {
+		case object.RETURN_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
 			return res
 		}
 	}
@@ -290,31 +665,105 @@ func evalFunctionCall(functionCall *ast.FunctionCall, env *object.Environment) o
 		return evalBuiltInFun(functionCall, env)
 	}
 
-	fmt.Printf("function %s is not defined\n", functionCall.Name)
-	os.Exit(1)
+	return object.NewError(object.NAME_ERROR, "function %s is not defined", functionCall.Name)
+}
 
-	return nil
+// call a function by name with arguments that have already been evaluated, functions defined in the
+// environment take precedence over builtins with the same name
+func CallFunction(name string, args []object.Object, env *object.Environment) object.Object {
+	if obj, ok := env.Get(name); ok {
+		function, ok := obj.(*object.FunctionObject)
+		if !ok {
+			return object.NewError(object.TYPE_ERROR, "%s is not a function", name)
+		}
+
+		return applyFunction(function, name, args)
+	} else if fn, ok := stdlib.BuiltInFuns[name]; ok {
+		return fn(env.Runtime(), args...)
+	}
+
+	return object.NewError(object.NAME_ERROR, "function %s is not defined", name)
 }
 
 func evalUserDefinedFun(functionCall *ast.FunctionCall, env *object.Environment) object.Object {
 	obj, ok := env.Get(functionCall.Name)
 	if !ok {
-		fmt.Printf("function %s is not defined\n", functionCall.Name)
-		os.Exit(1)
+		return object.NewError(object.NAME_ERROR, "function %s is not defined", functionCall.Name)
+	}
+
+	function, ok := obj.(*object.FunctionObject)
+	if !ok {
+		return object.NewError(object.TYPE_ERROR, "%s is not a function", functionCall.Name)
+	}
+
+	args, err := evalArgs(functionCall.Args, env)
+	if err != nil {
+		return err
+	}
+
+	return applyFunction(function, functionCall.Name, args)
+}
+
+// call the function returned by an expression, e.g. makeCounter()()
+func evalCallExpression(call *ast.CallExpression, env *object.Environment) object.Object {
+	obj := Eval(call.Function, env)
+	if isError(obj) {
+		return obj
+	}
+
+	function, ok := obj.(*object.FunctionObject)
+	if !ok {
+		return object.NewError(object.TYPE_ERROR, "%s is not a function", call.Function.ToString())
+	}
+
+	args, err := evalArgs(call.Args, env)
+	if err != nil {
+		return err
+	}
+
+	return applyFunction(function, call.Function.ToString(), args)
+}
+
+// evaluate the arguments to a function call, stopping at the first argument that raises an error
+func evalArgs(argExprs []ast.Expression, env *object.Environment) ([]object.Object, *object.ErrorObject) {
+	args := []object.Object{}
+
+	for i := range argExprs {
+		arg := Eval(argExprs[i], env)
+		if errObj, ok := arg.(*object.ErrorObject); ok {
+			return nil, errObj
+		}
+
+		args = append(args, arg)
 	}
 
-	function := obj.(*object.FunctionObject)
+	return args, nil
+}
+
+// run a user defined function with arguments that have already been evaluated
+func applyFunction(function *object.FunctionObject, name string, args []object.Object) object.Object {
+	if len(args) != len(function.Args) {
+		return object.NewError(object.ARGUMENT_ERROR, "expected %d arguments for function %s, received %d", len(function.Args), name, len(args))
+	}
 
-	if len(functionCall.Args) != len(function.Args) {
-		fmt.Printf("expected %d arguments for function %s, received %d\n", len(function.Args), functionCall.Name, len(functionCall.Args))
-		os.Exit(1)
+	// the depth is counted by the runtime, so recursion in one interpreter doesn't count against another
+	rt := function.Env.Runtime()
+	if rt.CallDepth >= rt.MaxCallDepth {
+		return object.NewError(object.RECURSION_ERROR, "maximum recursion depth exceeded")
 	}
 
-	childEnv := object.CreateChildEnvironment(env)
+	rt.CallDepth++
+	defer func() {
+		rt.CallDepth--
+	}()
+
+	// functions are lexically scoped, so the body runs in a child of the environment the function was defined in
+	childEnv := object.CreateChildEnvironment(function.Env)
+	childEnv.SetFile(function.File)
 
 	// assign function args as values in child environment
 	for i := range function.Args {
-		childEnv.Set(function.Args[i], Eval(functionCall.Args[i], env), true)
+		childEnv.Set(function.Args[i], args[i], true)
 	}
 
 	res := evalStatements(function.Statements, childEnv)
@@ -328,56 +777,135 @@ func evalUserDefinedFun(functionCall *ast.FunctionCall, env *object.Environment)
 }
 
 func evalBuiltInFun(functionCall *ast.FunctionCall, env *object.Environment) object.Object {
-	args := []object.Object{}
-
-	for i := range functionCall.Args {
-		args = append(args, Eval(functionCall.Args[i], env))
+	args, err := evalArgs(functionCall.Args, env)
+	if err != nil {
+		return err
 	}
 
-	return stdlib.BuiltInFuns[functionCall.Name](args...)
+	return stdlib.BuiltInFuns[functionCall.Name](env.Runtime(), args...)
 }
 
 func evalObjFunCall(objFunCall *ast.ObjectFunctionExpression, env *object.Environment) object.Object {
-	args := []object.Object{Eval(objFunCall.Object, env)}
+	obj := Eval(objFunCall.Object, env)
+	if isError(obj) {
+		return obj
+	}
+
+	if member, ok := objFunCall.Function.(*ast.IdentifierExpression); ok {
+		return evalMemberAccess(obj, member.Value)
+	}
+
 	fnCall := objFunCall.Function.(*ast.FunctionCall)
 
-	for i := range fnCall.Args {
-		args = append(args, Eval(fnCall.Args[i], env))
+	if module, ok := obj.(*object.ModuleObject); ok {
+		return evalModuleFunCall(module, fnCall, env)
+	}
+
+	fn, ok := stdlib.BuiltInFuns[fnCall.Name]
+	if !ok {
+		return object.NewError(object.NAME_ERROR, "function %s is not defined", fnCall.Name)
+	}
+
+	args, err := evalArgs(fnCall.Args, env)
+	if err != nil {
+		return err
 	}
 
-	if fn, ok := stdlib.BuiltInFuns[fnCall.Name]; ok {
-		return fn(args...)
+	return fn(env.Runtime(), append([]object.Object{obj}, args...)...)
+}
+
+// members of a map can be accessed by name, e.g. config.port is the same as config["port"]
+// modules also expose their top-level definitions as members
+func evalMemberAccess(obj object.Object, member string) object.Object {
+	if module, ok := obj.(*object.ModuleObject); ok {
+		val, ok := module.Members.Get(member)
+		if !ok {
+			return object.NewError(object.NAME_ERROR, "module %s has no member %s", module.Path, member)
+		}
+
+		return val
 	}
 
-	return &object.ErrorObject{Message: fmt.Sprintf("function %s is not defined\n", fnCall.Name)}
+	mapObj, ok := obj.(*object.MapObject)
+	if !ok {
+		return object.NewError(object.TYPE_ERROR, "object of type %s has no member %s", obj.Type(), member)
+	}
+
+	val, ok := mapObj.Get(&object.StringObject{Value: member})
+	if !ok {
+		return object.NewError(object.KEY_ERROR, "key %s not found in map", member)
+	}
+
+	return val
 }
 
 func evalArrayExpression(items []ast.Expression, env *object.Environment) object.Object {
 	arr := &object.ArrayObject{Items: []object.Object{}}
 
 	for i := range items {
-		arr.Items = append(arr.Items, Eval(items[i], env))
+		item := Eval(items[i], env)
+		if isError(item) {
+			return item
+		}
+
+		arr.Items = append(arr.Items, item)
 	}
 
 	return arr
 }
 
-func evalArrayIndexExpression(arrIdxExpr *ast.ArrayIndexExpression, env *object.Environment) object.Object {
-	arr, ok := Eval(arrIdxExpr.Arr, env).(*object.ArrayObject)
+func evalMapExpression(mapExpr *ast.MapExpression, env *object.Environment) object.Object {
+	mapObj := object.NewMapObject()
 
-	if !ok {
-		return &object.ErrorObject{Message: fmt.Sprintf("cannot index object of type %s", arr.Type())}
+	for i := range mapExpr.Keys {
+		key := Eval(mapExpr.Keys[i], env)
+		if isError(key) {
+			return key
+		}
+
+		hashable, err := MapKey(key)
+		if err != nil {
+			return err
+		}
+
+		val := Eval(mapExpr.Values[i], env)
+		if isError(val) {
+			return val
+		}
+
+		mapObj.Set(hashable, val)
 	}
 
-	idx, ok := Eval(arrIdxExpr.Index, env).(*object.IntegerObject)
+	return mapObj
+}
 
-	if !ok {
-		return &object.ErrorObject{Message: fmt.Sprintf("cannot use object of type %s as index", idx.Type())}
+// handles indexing both arrays and maps
+func evalArrayIndexExpression(arrIdxExpr *ast.ArrayIndexExpression, env *object.Environment) object.Object {
+	obj := Eval(arrIdxExpr.Arr, env)
+	if isError(obj) {
+		return obj
+	}
+
+	idxObj := Eval(arrIdxExpr.Index, env)
+	if isError(idxObj) {
+		return idxObj
 	}
 
-	if int(idx.Value) >= len(arr.Items) {
-		return &object.ErrorObject{Message: "array index out of bounds"}
+	return Index(obj, idxObj)
+}
+
+// evaluate each part of the string and join their string representations
+func evalInterpolatedString(str *ast.InterpolatedString, env *object.Environment) object.Object {
+	var sb strings.Builder
+
+	for _, part := range str.Parts {
+		val := Eval(part, env)
+		if isError(val) {
+			return val
+		}
+
+		sb.WriteString(val.ToString())
 	}
 
-	return arr.Items[idx.Value]
+	return &object.StringObject{Value: sb.String()}
 }
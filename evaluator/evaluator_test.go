@@ -0,0 +1,710 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/MarkyMan4/yetti/lexer"
+	"github.com/MarkyMan4/yetti/object"
+	"github.com/MarkyMan4/yetti/parser"
+)
+
+// evaluate a program and return the environment it ran in
+func evalProgram(t *testing.T, input string) *object.Environment {
+	return evalProgramIn(t, input, object.NewEnvironment())
+}
+
+func evalProgramIn(t *testing.T, input string, env *object.Environment) *object.Environment {
+	l := lexer.NewLexer(input)
+	p := parser.NewParser(l)
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("failed to parse program: %v\n", p.Errors)
+	}
+
+	for i := range prog.Statements {
+		res := Eval(prog.Statements[i], env)
+
+		if errObj, ok := res.(*object.ErrorObject); ok {
+			t.Fatalf("uncaught error: %s\n", errObj.ToString())
+		}
+	}
+
+	return env
+}
+
+// evaluate a program that is expected to end with an uncaught error
+func evalProgramError(t *testing.T, input string) *object.ErrorObject {
+	p := parser.NewParser(lexer.NewLexer(input))
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("failed to parse program: %v\n", p.Errors)
+	}
+
+	env := object.NewEnvironment()
+
+	for i := range prog.Statements {
+		if errObj, ok := Eval(prog.Statements[i], env).(*object.ErrorObject); ok {
+			return errObj
+		}
+	}
+
+	t.Fatal("expected program to raise an error")
+
+	return nil
+}
+
+// check the value of a variable by comparing its string representation
+func expectVar(t *testing.T, env *object.Environment, name string, expected string) {
+	obj, ok := env.Get(name)
+	if !ok {
+		t.Fatalf("variable %s is not defined\n", name)
+	}
+
+	if obj.ToString() != expected {
+		t.Fatalf("expected %s to be %s but got %s\n", name, expected, obj.ToString())
+	}
+}
+
+func TestIfElse(t *testing.T) {
+	input := `
+		fun classify(n) {
+			if(n < 0) {
+				return "negative";
+			} else if(n == 0) {
+				return "zero";
+			} else {
+				return "positive";
+			}
+		}
+
+		var a = classify(-3);
+		var b = classify(0);
+		var c = classify(7);
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "a", "negative")
+	expectVar(t, env, "b", "zero")
+	expectVar(t, env, "c", "positive")
+}
+
+func TestForLoop(t *testing.T) {
+	input := `
+		var total = 0;
+		for(var i = 1; i <= 4; i += 1) {
+			total += i;
+		}
+
+		var j = 0;
+		for(j = 10; j < 100;) {
+			j *= 2;
+		}
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "total", "10")
+	expectVar(t, env, "j", "160")
+
+	// the loop variable is scoped to the loop
+	if _, ok := env.Get("i"); ok {
+		t.Fatal("expected i to only be defined inside the loop")
+	}
+}
+
+func TestForInLoop(t *testing.T) {
+	input := `
+		var total = 0;
+		for(x in [1, 2, 3]) {
+			total += x;
+		}
+
+		var reversed = "";
+		for(ch in "abc") {
+			reversed = ch + reversed;
+		}
+
+		fun find(xs, target) {
+			var idx = 0;
+			for(x in xs) {
+				if(x == target) {
+					return idx;
+				}
+
+				idx += 1;
+			}
+
+			return -1;
+		}
+
+		var found = find(["a", "b", "c"], "b");
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "total", "6")
+	expectVar(t, env, "reversed", "cba")
+	expectVar(t, env, "found", "1")
+}
+
+func TestBreakContinue(t *testing.T) {
+	input := `
+		var evens = 0;
+		for(var i = 0; i < 10; i += 1) {
+			if(i >= 6) {
+				break;
+			}
+
+			if(i == 1) {
+				continue;
+			} else if(i == 3) {
+				continue;
+			} else if(i == 5) {
+				continue;
+			}
+
+			evens += 1;
+		}
+
+		var n = 0;
+		while(true) {
+			n += 1;
+			if(n < 5) {
+				continue;
+			}
+
+			break;
+		}
+
+		fun firstOver(xs, limit) {
+			for(x in xs) {
+				while(true) {
+					break;
+				}
+
+				if(x > limit) {
+					return x;
+				}
+			}
+
+			return 0;
+		}
+
+		var over = firstOver([1, 5, 10, 20], 7);
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "evens", "3")
+	expectVar(t, env, "n", "5")
+	expectVar(t, env, "over", "10")
+}
+
+func TestLogicalOperators(t *testing.T) {
+	input := `
+		var calls = 0;
+		fun check() {
+			calls += 1;
+			return true;
+		}
+
+		var a = true && false;
+		var b = false || true;
+		var c = !true;
+		var d = false && check();
+		var e = true || check();
+		var f = true && check();
+
+		var xs = [1, 2];
+		var i = 5;
+		var guard = i < xs.length() && xs[i] > 0;
+
+		var g = true == true;
+		var h = true != false;
+		var j = 1 != 2;
+		var k = "a" != "a";
+		var m = 1 == "1";
+		var n = 1.5 != 2;
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "a", "false")
+	expectVar(t, env, "b", "true")
+	expectVar(t, env, "c", "false")
+	expectVar(t, env, "d", "false")
+	expectVar(t, env, "e", "true")
+	expectVar(t, env, "f", "true")
+	expectVar(t, env, "calls", "1")
+	expectVar(t, env, "guard", "false")
+	expectVar(t, env, "g", "true")
+	expectVar(t, env, "h", "true")
+	expectVar(t, env, "j", "true")
+	expectVar(t, env, "k", "false")
+	expectVar(t, env, "m", "false")
+	expectVar(t, env, "n", "true")
+}
+
+func TestOperatorPrecedence(t *testing.T) {
+	input := `
+		var a = 2 * 3 + 4;
+		var b = 2 + 3 * 4;
+		var c = 10 - 2 - 3;
+		var d = (2 + 3) * 4;
+		var e = 100 / 10 / 2;
+		var f = 1 + 2 < 4 && 10 - 5 == 5;
+		var xs = [1, 2, 3];
+		var g = xs[1] * xs[2] - xs.length();
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "a", "10")
+	expectVar(t, env, "b", "14")
+	expectVar(t, env, "c", "5")
+	expectVar(t, env, "d", "20")
+	expectVar(t, env, "e", "5")
+	expectVar(t, env, "f", "true")
+	expectVar(t, env, "g", "3")
+}
+
+func TestPrefixOperators(t *testing.T) {
+	input := `
+		var a = -5;
+		var b = -2.5 * 2;
+		var c = 10 - -3;
+		var d = +a;
+		var e = !(1 > 2);
+
+		fun negate(n) {
+			return -n;
+		}
+
+		var f = negate(a);
+
+		var g = "";
+		try {
+			g = -"abc";
+		} catch(e) {
+			g = e.message;
+		}
+
+		var h = "";
+		try {
+			h = !1;
+		} catch(e) {
+			h = e.message;
+		}
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "a", "-5")
+	expectVar(t, env, "b", "-5")
+	expectVar(t, env, "c", "13")
+	expectVar(t, env, "d", "-5")
+	expectVar(t, env, "e", "true")
+	expectVar(t, env, "f", "5")
+	expectVar(t, env, "g", "unsupported operator '-' for type STRING")
+	expectVar(t, env, "h", "unsupported operator '!' for type INTEGER")
+}
+
+func TestNull(t *testing.T) {
+	input := `
+		var a = null;
+		var b = a == null;
+		var c = a != null;
+		var d = 0 == null;
+
+		fun nothing() {}
+
+		var e = nothing() == null;
+		var f = [null, 1];
+
+		var g = "";
+		try {
+			if (null) {
+				g = "truthy";
+			}
+		} catch(e) {
+			g = e.kind;
+		}
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "a", "null")
+	expectVar(t, env, "b", "true")
+	expectVar(t, env, "c", "false")
+	expectVar(t, env, "d", "false")
+	expectVar(t, env, "e", "true")
+	expectVar(t, env, "f", "[null,1]")
+	expectVar(t, env, "g", "TypeError")
+}
+
+func TestClosures(t *testing.T) {
+	input := `
+		fun makeCounter() {
+			var count = 0;
+
+			return fun() {
+				count += 1;
+				return count;
+			};
+		}
+
+		var counter = makeCounter();
+		counter();
+		counter();
+		var a = counter();
+
+		// each counter has its own environment
+		var other = makeCounter();
+		var b = other();
+
+		fun makeAdder(n) {
+			fun add(x) {
+				return x + n;
+			}
+
+			return add;
+		}
+
+		var c = makeAdder(10)(5);
+
+		fun apply(f, x) {
+			return f(x);
+		}
+
+		var d = apply(fun(x) { return x * x; }, 4);
+
+		// functions see the variables where they're defined, not where they're called
+		var scope = "global";
+		fun getScope() {
+			return scope;
+		}
+
+		fun callGetScope() {
+			var scope = "local";
+			return getScope();
+		}
+
+		var e = callGetScope();
+		var fns = [fun(x) { return x + 1; }];
+		var f = fns[0](1);
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "a", "3")
+	expectVar(t, env, "b", "1")
+	expectVar(t, env, "c", "15")
+	expectVar(t, env, "d", "16")
+	expectVar(t, env, "e", "global")
+	expectVar(t, env, "f", "2")
+}
+
+func TestMaps(t *testing.T) {
+	input := `
+		var m = {"name": "yetti", 1: "one", 2.5: "two and a half", true: "yes"};
+		var a = m["name"];
+		var b = m[1.0];
+		var c = m[2.5];
+		var d = m[1 == 1];
+		var size = m.length();
+		var hasName = m.has("name");
+		var hasOther = has(m, "other");
+
+		m = m.delete(1);
+		var k = m.keys();
+		var v = values(m);
+
+		var total = 0;
+		var counts = {"x": 1, "y": 2};
+		for(key in counts) {
+			total += counts[key];
+		}
+
+		var missing = "";
+		try {
+			missing = m["nope"];
+		} catch(e) {
+			missing = e.message;
+		}
+
+		var badKey = "";
+		try {
+			badKey = {[1]: 2};
+		} catch(e) {
+			badKey = e.message;
+		}
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "a", "yetti")
+	expectVar(t, env, "b", "one")
+	expectVar(t, env, "c", "two and a half")
+	expectVar(t, env, "d", "yes")
+	expectVar(t, env, "size", "4")
+	expectVar(t, env, "hasName", "true")
+	expectVar(t, env, "hasOther", "false")
+	expectVar(t, env, "k", "[name,2.5,true]")
+	expectVar(t, env, "v", "[yetti,two and a half,yes]")
+	expectVar(t, env, "total", "3")
+	expectVar(t, env, "missing", "key nope not found in map")
+	expectVar(t, env, "badKey", "cannot use object of type ARRAY as map key")
+}
+
+func TestAssignTargets(t *testing.T) {
+	input := `
+		var xs = [1, 2, 3];
+		xs[2] = 10;
+		xs[0] += 5;
+
+		var grid = [[0, 0], [0, 0]];
+		for(var i = 0; i < 2; i += 1) {
+			for(var j = 0; j < 2; j += 1) {
+				grid[i][j] += i + j;
+			}
+		}
+
+		grid[1][1] *= 3;
+
+		var m = {"count": 1};
+		m["count"] += 1;
+		m["new"] = "added";
+		m.count *= 10;
+		m.other = 4;
+		m.other /= 2;
+
+		var nested = {"inner": [1, 2]};
+		nested["inner"][1] -= 2;
+		nested.inner[0] = m.count;
+
+		var x = 4;
+		x /= 2;
+
+		xs.append(11);
+		var count = m.count;
+
+		var words = ["b", "a", "b"];
+		var counts = {"a": 0, "b": 0};
+		for(var i = 0; i < words.length(); i += 1) {
+			counts[words[i]] += 1;
+		}
+
+		var order = [1, 0];
+		var ys = [0, 0];
+		ys[order[0]] = grid[order[0]][order[1]];
+		var picked = words[order[0]];
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "xs", "[6,2,10,11]")
+	expectVar(t, env, "grid", "[[0,1],[1,6]]")
+	expectVar(t, env, "m", "{count:20,new:added,other:2}")
+	expectVar(t, env, "count", "20")
+	expectVar(t, env, "nested", "{inner:[20,0]}")
+	expectVar(t, env, "x", "2")
+	expectVar(t, env, "counts", "{a:1,b:2}")
+	expectVar(t, env, "ys", "[0,1]")
+	expectVar(t, env, "picked", "a")
+}
+
+func TestTryCatch(t *testing.T) {
+	input := `
+		var kind = "";
+		var msg = "";
+		try {
+			var xs = [1, 2];
+			xs[5] = 1;
+			msg = "not reached";
+		} catch(e) {
+			kind = e.kind;
+			msg = e.message;
+		}
+
+		fun divide(a, b) {
+			if(b == 0) {
+				throw {"kind": "ValueError", "message": "cannot divide by zero"};
+			}
+
+			return a / b;
+		}
+
+		// errors propagate up through function calls
+		fun calculate() {
+			var res = divide(1, 0);
+			return res + 1;
+		}
+
+		var thrownKind = "";
+		try {
+			calculate();
+		} catch(err) {
+			thrownKind = err.kind;
+		}
+
+		var stringThrown = "";
+		try {
+			throw "something went wrong";
+		} catch(e) {
+			stringThrown = e.kind + ": " + e.message;
+		}
+
+		// errors can be rethrown and caught again
+		var rethrown = "";
+		try {
+			try {
+				undefinedFunction();
+			} catch(e) {
+				throw e;
+			}
+		} catch(e) {
+			rethrown = e.kind;
+		}
+
+		var caught = false;
+		try {
+			var x = 1 + "a";
+		} catch {
+			caught = true;
+		}
+
+		var noError = "";
+		try {
+			noError = "ok";
+		} catch(e) {
+			noError = "caught";
+		}
+
+		fun firstNegative(xs) {
+			for(x in xs) {
+				try {
+					if(x < 0) {
+						return x;
+					}
+				} catch(e) {
+					return 0;
+				}
+			}
+
+			return 0;
+		}
+
+		var neg = firstNegative([1, -2, 3]);
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "kind", "IndexError")
+	expectVar(t, env, "msg", "array index 5 out of bounds in assignment to xs[5]")
+	expectVar(t, env, "thrownKind", "ValueError")
+	expectVar(t, env, "stringThrown", "Error: something went wrong")
+	expectVar(t, env, "rethrown", "NameError")
+	expectVar(t, env, "caught", "true")
+	expectVar(t, env, "noError", "ok")
+	expectVar(t, env, "neg", "-2")
+}
+
+func TestUncaughtErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var x = y;", "NameError: identifier y is not defined"},
+		{"var x = 1 + true;", "TypeError: unsupported operator '+' for types INTEGER, BOOLEAN"},
+		{"if(1) { var x = 1; }", "TypeError: condition must return a boolean, got INTEGER"},
+		{"fun f(a) { return a; } f(1, 2);", "ArgumentError: expected 1 arguments for function f, received 2"},
+		{"var s = \"abc\"; var x = s.substr(1, 10);", "IndexError: substring indices 1:10 out of bounds for string of length 3"},
+		{"fun f() { throw \"bad\"; } fun g() { f(); print(\"not reached\"); } g();", "Error: bad"},
+		{"x = 1;", "NameError: variable x has not been declared"},
+		{"var m = {}; m.a += 1;", "KeyError: key a not found in map in assignment to m.a"},
+	}
+
+	for _, tt := range tests {
+		errObj := evalProgramError(t, tt.input)
+
+		if errObj.ToString() != tt.expected {
+			t.Fatalf("expected error %s but got %s\n", tt.expected, errObj.ToString())
+		}
+	}
+}
+
+func TestLongWhileLoop(t *testing.T) {
+	input := `
+		var i = 0;
+		var total = 0;
+		while(i < 200000) {
+			i += 1;
+			if(i == 10) {
+				continue;
+			}
+			total += 1;
+		}
+	`
+	// each iteration used to add to the Go stack
+	env := evalProgram(t, input)
+	expectVar(t, env, "i", "200000")
+	expectVar(t, env, "total", "199999")
+}
+
+func TestRecursionDepth(t *testing.T) {
+	input := `
+		fun down(n) {
+			if(n == 0) {
+				return 0;
+			}
+			return down(n - 1);
+		}
+
+		var shallow = down(49);
+		var caught = "";
+
+		try {
+			down(50);
+		} catch(e) {
+			caught = e.kind + ": " + e.message;
+		}
+
+		// the depth is back to normal once the error has been caught
+		var again = down(49);
+	`
+
+	env := object.NewEnvironment()
+	env.Runtime().MaxCallDepth = 50
+
+	evalProgramIn(t, input, env)
+	expectVar(t, env, "shallow", "0")
+	expectVar(t, env, "caught", "RecursionError: maximum recursion depth exceeded")
+	expectVar(t, env, "again", "0")
+}
+
+func TestErrorPositions(t *testing.T) {
+	input := "var x = 1;\nfun f(a) {\n    return a + true;\n}\nvar y = f(x);"
+	errObj := evalProgramError(t, input)
+
+	// the error is reported where it was raised, not where the function was called
+	if errObj.Pos.String() != "3:12" {
+		t.Fatalf("expected error at 3:12 but got %s\n", errObj.Pos)
+	}
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	input := `
+		var n = 4;
+		var m = {"key": "value"};
+		fun greet(name) {
+			return "hello ${name}!";
+		}
+
+		var s1 = "count: ${n + 1}";
+		var s2 = "key: ${m["key"]}, list: ${[1, 2]}";
+		var s3 = "${greet("bob")} ${"nested ${n * 2}"}";
+		var s4 = "cost: \${n}";
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "s1", "count: 5")
+	expectVar(t, env, "s2", "key: value, list: [1,2]")
+	expectVar(t, env, "s3", "hello bob! nested 8")
+	expectVar(t, env, "s4", "cost: ${n}")
+
+	errObj := evalProgramError(t, "var n = 1;\nvar s = \"n: ${n + true}\";")
+	if errObj.Pos.String() != "2:15" {
+		t.Fatalf("expected error at 2:15 but got %s\n", errObj.Pos)
+	}
+}
@@ -0,0 +1,124 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MarkyMan4/yetti/ast"
+	"github.com/MarkyMan4/yetti/lexer"
+	"github.com/MarkyMan4/yetti/object"
+	"github.com/MarkyMan4/yetti/parser"
+)
+
+func evalImportStatement(importStmt *ast.ImportStatement, env *object.Environment) object.Object {
+	path, err := ResolveImport(importStmt.Path, env.File(), env.Runtime().SearchPath)
+	if err != nil {
+		return err
+	}
+
+	// modules are cached by the runtime, so each module is only evaluated once per interpreter
+	module, ok := env.Runtime().Modules[path]
+	if !ok {
+		res := loadModule(path, env.Runtime())
+		if isError(res) {
+			return res
+		}
+
+		module = res.(*object.ModuleObject)
+	}
+
+	env.Set(importStmt.Alias, module, true)
+
+	return module
+}
+
+// find the file for an import, first relative to the importing file and then in each directory of the search path
+func ResolveImport(importPath string, importingFile string, searchPath []string) (string, *object.ErrorObject) {
+	dirs := []string{"."}
+
+	if filepath.IsAbs(importPath) {
+		dirs = []string{""}
+	} else if importingFile != "" {
+		dirs = []string{filepath.Dir(importingFile)}
+	}
+
+	dirs = append(dirs, searchPath...)
+
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, importPath)
+
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			absPath, err := filepath.Abs(candidate)
+			if err != nil {
+				return "", object.NewError(object.IMPORT_ERROR, "could not resolve module %s - %s", importPath, err.Error())
+			}
+
+			return absPath, nil
+		}
+	}
+
+	return "", object.NewError(object.IMPORT_ERROR, "could not find module %s", importPath)
+}
+
+// evaluate a module in its own environment and add it to the cache of the runtime importing it
+func loadModule(path string, rt *object.Runtime) object.Object {
+	for i := range rt.ImportStack {
+		if rt.ImportStack[i] == path {
+			cycle := append(append([]string{}, rt.ImportStack[i:]...), path)
+			return object.NewError(object.IMPORT_ERROR, "import cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return object.NewError(object.IMPORT_ERROR, "failed to read module %s - %s", path, err.Error())
+	}
+
+	p := parser.NewParser(lexer.NewLexer(string(src)))
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		return object.NewError(object.IMPORT_ERROR, "failed to parse module %s - %s", path, p.Errors[0])
+	}
+
+	rt.ImportStack = append(rt.ImportStack, path)
+	defer func() {
+		rt.ImportStack = rt.ImportStack[:len(rt.ImportStack)-1]
+	}()
+
+	moduleEnv := object.NewEnvironment()
+	moduleEnv.SetFile(path)
+	moduleEnv.SetRuntime(rt)
+
+	for i := range prog.Statements {
+		if res := Eval(prog.Statements[i], moduleEnv); isError(res) {
+			return res
+		}
+	}
+
+	module := &object.ModuleObject{Path: path, Members: moduleEnv}
+	rt.Modules[path] = module
+
+	return module
+}
+
+// call a function defined at the top level of a module, e.g. s.reverse("abc")
+func evalModuleFunCall(module *object.ModuleObject, fnCall *ast.FunctionCall, env *object.Environment) object.Object {
+	member, ok := module.Members.Get(fnCall.Name)
+	if !ok {
+		return object.NewError(object.NAME_ERROR, "module %s has no member %s", module.Path, fnCall.Name)
+	}
+
+	function, ok := member.(*object.FunctionObject)
+	if !ok {
+		return object.NewError(object.TYPE_ERROR, "%s is not a function", fnCall.Name)
+	}
+
+	args, err := evalArgs(fnCall.Args, env)
+	if err != nil {
+		return err
+	}
+
+	return applyFunction(function, fnCall.Name, args)
+}
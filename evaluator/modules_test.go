@@ -0,0 +1,149 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MarkyMan4/yetti/lexer"
+	"github.com/MarkyMan4/yetti/object"
+	"github.com/MarkyMan4/yetti/parser"
+)
+
+// write the given files into a temporary directory and return the path of the directory
+func writeModules(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestImport(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"lib/strings.yti": `
+			import "counter.yti" as c;
+
+			var separator = ", ";
+
+			fun join(xs) {
+				var res = "";
+				for(var i = 0; i < xs.length(); i += 1) {
+					if(i > 0) {
+						res += separator;
+					}
+					res += xs[i];
+				}
+				return res;
+			}
+
+			fun bump() {
+				return c.next();
+			}
+		`,
+		"lib/counter.yti": `
+			var count = 0;
+
+			fun next() {
+				count += 1;
+				return count;
+			}
+		`,
+	})
+
+	input := `
+		import "` + filepath.Join(dir, "lib/strings.yti") + `" as s;
+		import "` + filepath.Join(dir, "lib/counter.yti") + `" as c;
+
+		var joined = s.join(["a", "b", "c"]);
+		var sep = s.separator;
+		var first = s.bump();
+		var second = c.next();
+	`
+	env := evalProgram(t, input)
+
+	expectVar(t, env, "joined", "a, b, c")
+	expectVar(t, env, "sep", ", ")
+
+	// both imports of counter.yti share the same module, so the count carries over
+	expectVar(t, env, "first", "1")
+	expectVar(t, env, "second", "2")
+}
+
+func TestImportSearchPath(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"math.yti": "fun square(x) { return x * x; }",
+	})
+
+	env := object.NewEnvironment()
+	env.Runtime().SearchPath = []string{dir}
+	evalProgramIn(t, `import "math.yti" as m; var x = m.square(4);`, env)
+
+	expectVar(t, env, "x", "16")
+
+	// the search path belongs to the runtime, so other interpreters don't see it
+	errObj := evalProgramError(t, `import "math.yti" as m;`)
+	if errObj.Kind != object.IMPORT_ERROR {
+		t.Fatalf("expected an ImportError without the search path, got %s\n", errObj.ToString())
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"a.yti":      `import "b.yti" as b;`,
+		"b.yti":      `import "a.yti" as a;`,
+		"broken.yti": `var x 1;`,
+		"ok.yti":     `var x = 1;`,
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "` + filepath.Join(dir, "a.yti") + `" as a;`, "ImportError: import cycle detected"},
+		{`import "` + filepath.Join(dir, "missing.yti") + `" as m;`, "ImportError: could not find module"},
+		{`import "` + filepath.Join(dir, "broken.yti") + `" as b;`, "ImportError: failed to parse module"},
+		{`import "` + filepath.Join(dir, "ok.yti") + `" as m; var y = m.y;`, "NameError: module"},
+		{`import "` + filepath.Join(dir, "ok.yti") + `" as m; m.x();`, "TypeError: x is not a function"},
+	}
+
+	for _, tt := range tests {
+		errObj := evalProgramError(t, tt.input)
+
+		if !strings.HasPrefix(errObj.ToString(), tt.expected) {
+			t.Fatalf("expected error starting with %s but got %s\n", tt.expected, errObj.ToString())
+		}
+	}
+
+}
+
+func TestImportRelativeToFile(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"lib/ok.yti": `var x = 1;`,
+	})
+
+	// imports are resolved relative to the directory of the file doing the import
+	env := object.NewEnvironment()
+	env.SetFile(filepath.Join(dir, "main.yti"))
+
+	prog := parser.NewParser(lexer.NewLexer(`import "lib/ok.yti" as m; var x = m.x;`)).Parse()
+
+	for i := range prog.Statements {
+		if res := Eval(prog.Statements[i], env); isError(res) {
+			t.Fatalf("uncaught error: %s\n", res.ToString())
+		}
+	}
+
+	expectVar(t, env, "x", "1")
+}
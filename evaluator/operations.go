@@ -0,0 +1,223 @@
+package evaluator
+
+import "github.com/MarkyMan4/yetti/object"
+
+// The functions in this file implement the parts of the language's semantics that don't depend on
+// walking the AST. They are exported so the bytecode VM behaves exactly like the evaluator.
+
+func ApplyInfix(op string, left object.Object, right object.Object) object.Object {
+	return evalInfixExpression(op, left, right)
+}
+
+func ApplyPrefix(op string, right object.Object) object.Object {
+	return evalPrefixExpression(op, right)
+}
+
+func ApplyAssignOp(assignOp string, left object.Object, right object.Object) object.Object {
+	return evalAssignOp(assignOp, left, right)
+}
+
+func MemberOf(obj object.Object, member string) object.Object {
+	return evalMemberAccess(obj, member)
+}
+
+// conditions of if statements and loops must be booleans
+func CheckCondition(condResult object.Object) *object.ErrorObject {
+	if condResult.Type() != object.BOOLEAN_OBJ {
+		return object.NewError(object.TYPE_ERROR, "condition must return a boolean, got %s", condResult.Type())
+	}
+
+	return nil
+}
+
+// both sides of && and || must be booleans, side is "left" or "right" and is used in the error message
+func LogicalOperand(op string, side string, obj object.Object) (bool, *object.ErrorObject) {
+	b, ok := obj.(*object.BooleanObject)
+	if !ok {
+		return false, object.NewError(object.TYPE_ERROR, "%s side of '%s' must be a boolean, got %s", side, op, obj.Type())
+	}
+
+	return b.Value, nil
+}
+
+func MapKey(key object.Object) (object.Hashable, *object.ErrorObject) {
+	hashable, ok := key.(object.Hashable)
+	if !ok {
+		return nil, object.NewError(object.TYPE_ERROR, "cannot use object of type %s as map key", key.Type())
+	}
+
+	return hashable, nil
+}
+
+// get the items a for-in loop iterates over one at a time
+func Iterate(collection object.Object) (*object.IteratorObject, *object.ErrorObject) {
+	var items []object.Object
+
+	switch collection := collection.(type) {
+	case *object.IteratorObject:
+		return collection, nil
+	case *object.ArrayObject:
+		// copy the items so appending to the array in the loop doesn't change the number of iterations
+		items = append([]object.Object{}, collection.Items...)
+	case *object.StringObject:
+		for _, ch := range collection.Value {
+			items = append(items, &object.StringObject{Value: string(ch)})
+		}
+	case *object.MapObject:
+		// iterating over a map gives its keys
+		for _, hashKey := range collection.Keys {
+			items = append(items, collection.Pairs[hashKey].Key)
+		}
+	default:
+		return nil, object.NewError(object.TYPE_ERROR, "cannot iterate over object of type %s", collection.Type())
+	}
+
+	next := 0
+
+	return &object.IteratorObject{Next: func() (object.Object, bool, *object.ErrorObject) {
+		if next >= len(items) {
+			return nil, false, nil
+		}
+
+		next++
+
+		return items[next-1], true, nil
+	}}, nil
+}
+
+// turn a thrown value into the error it raises
+func ThrowValue(val object.Object) object.Object {
+	switch val := val.(type) {
+	case *object.ErrorObject:
+		return val
+	case *object.StringObject:
+		return object.NewError(object.ERROR, "%s", val.Value)
+	case *object.MapObject:
+		msg, ok := val.Get(&object.StringObject{Value: "message"})
+		if !ok {
+			return object.NewError(object.VALUE_ERROR, "thrown map must have a message")
+		}
+
+		kind := object.ERROR
+		if kindObj, ok := val.Get(&object.StringObject{Value: "kind"}); ok {
+			kind = kindObj.ToString()
+		}
+
+		return object.NewError(kind, "%s", msg.ToString())
+	default:
+		return object.NewError(object.TYPE_ERROR, "cannot throw object of type %s", val.Type())
+	}
+}
+
+// the value bound to the identifier of a catch block
+func CaughtError(errObj *object.ErrorObject) object.Object {
+	caught := object.NewMapObject()
+	caught.Set(&object.StringObject{Value: "kind"}, &object.StringObject{Value: errObj.Kind})
+	caught.Set(&object.StringObject{Value: "message"}, &object.StringObject{Value: errObj.Message})
+
+	return caught
+}
+
+func Index(obj object.Object, idxObj object.Object) object.Object {
+	switch obj := obj.(type) {
+	case *object.ArrayObject:
+		idx, ok := idxObj.(*object.IntegerObject)
+		if !ok {
+			return object.NewError(object.TYPE_ERROR, "cannot use object of type %s as index", idxObj.Type())
+		}
+
+		if idx.Value < 0 || int(idx.Value) >= len(obj.Items) {
+			return object.NewError(object.INDEX_ERROR, "array index %d out of bounds", idx.Value)
+		}
+
+		return obj.Items[idx.Value]
+	case *object.MapObject:
+		key, ok := idxObj.(object.Hashable)
+		if !ok {
+			return object.NewError(object.TYPE_ERROR, "cannot use object of type %s as map key", idxObj.Type())
+		}
+
+		val, ok := obj.Get(key)
+		if !ok {
+			return object.NewError(object.KEY_ERROR, "key %s not found in map", idxObj.ToString())
+		}
+
+		return val
+	default:
+		return object.NewError(object.TYPE_ERROR, "cannot index object of type %s", obj.Type())
+	}
+}
+
+// checks done on the target of an index assignment before the right hand side is evaluated,
+// target is the source of the target expression and is used in error messages
+func CheckIndexTarget(obj object.Object, idxObj object.Object, target string) *object.ErrorObject {
+	switch obj := obj.(type) {
+	case *object.ArrayObject:
+		idx, ok := idxObj.(*object.IntegerObject)
+		if !ok {
+			return object.NewError(object.TYPE_ERROR, "cannot use object of type %s as index in assignment to %s", idxObj.Type(), target)
+		}
+
+		if idx.Value < 0 || int(idx.Value) >= len(obj.Items) {
+			return object.NewError(object.INDEX_ERROR, "array index %d out of bounds in assignment to %s", idx.Value, target)
+		}
+	case *object.MapObject:
+		if _, ok := idxObj.(object.Hashable); !ok {
+			return object.NewError(object.TYPE_ERROR, "cannot use object of type %s as map key in assignment to %s", idxObj.Type(), target)
+		}
+	default:
+		return object.NewError(object.TYPE_ERROR, "cannot index object of type %s in assignment to %s", obj.Type(), target)
+	}
+
+	return nil
+}
+
+// only maps have members that can be assigned to
+func CheckMemberTarget(obj object.Object, member string) *object.ErrorObject {
+	if _, ok := obj.(*object.MapObject); !ok {
+		return object.NewError(object.TYPE_ERROR, "cannot assign member %s of object of type %s", member, obj.Type())
+	}
+
+	return nil
+}
+
+// assign to an array index or map key that has already been checked with CheckIndexTarget or CheckMemberTarget
+func AssignIndex(obj object.Object, idxObj object.Object, assignOp string, right object.Object, target string) object.Object {
+	switch obj := obj.(type) {
+	case *object.ArrayObject:
+		idx := idxObj.(*object.IntegerObject)
+
+		val := evalAssignOp(assignOp, obj.Items[idx.Value], right)
+		if isError(val) {
+			return val
+		}
+
+		obj.Items[idx.Value] = val
+
+		return val
+	case *object.MapObject:
+		key := idxObj.(object.Hashable)
+
+		// plain assignment can add a new key, other operators need an existing value
+		if assignOp == "=" {
+			obj.Set(key, right)
+			return right
+		}
+
+		left, ok := obj.Get(key)
+		if !ok {
+			return object.NewError(object.KEY_ERROR, "key %s not found in map in assignment to %s", key.ToString(), target)
+		}
+
+		val := evalAssignOp(assignOp, left, right)
+		if isError(val) {
+			return val
+		}
+
+		obj.Set(key, val)
+
+		return val
+	default:
+		return object.NewError(object.TYPE_ERROR, "cannot index object of type %s in assignment to %s", obj.Type(), target)
+	}
+}
@@ -0,0 +1,103 @@
+package yetti
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MarkyMan4/yetti/compiler"
+	"github.com/MarkyMan4/yetti/object"
+	"github.com/MarkyMan4/yetti/vm"
+)
+
+var update = flag.Bool("update", false, "update the expected output of the examples")
+
+// input given to examples that read from stdin
+const exampleInput = "yetti\nfrom\nstdin\n"
+
+// run an example with the evaluator, returning what it wrote to stdout and stderr followed by the
+// error that stopped it, if there was one
+func runExample(path string) string {
+	var out bytes.Buffer
+
+	in := NewInterpreter()
+	in.SetIO(strings.NewReader(exampleInput), &out, &out)
+
+	if err := in.RunFile(path); err != nil {
+		fmt.Fprintln(&out, err)
+	}
+
+	return out.String()
+}
+
+// same as runExample, but the example is compiled and run on the VM
+func runExampleVM(path string) string {
+	var out bytes.Buffer
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err.Error()
+	}
+
+	prog, err := Parse(path, string(src))
+	if err != nil {
+		return err.Error()
+	}
+
+	bytecode, compileErr := compiler.NewCompiler(path).Compile(prog)
+	if compileErr != nil {
+		return compileErr.Error()
+	}
+
+	machine := vm.NewVM(object.NewRuntime(strings.NewReader(exampleInput), &out, &out))
+	if errObj := machine.Run(bytecode); errObj != nil {
+		fmt.Fprintln(&out, NewRuntimeError(errObj, path, string(src)))
+	}
+
+	return out.String()
+}
+
+// the output of each example is compared with testdata/examples/<name>.out, run the tests
+// with -update to write the output of the evaluator to those files
+func TestExamples(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("examples", "*.yti"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".yti")
+		golden := filepath.Join("testdata", "examples", name+".out")
+
+		t.Run(name, func(t *testing.T) {
+			actual := runExample(path)
+
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(golden, []byte(actual), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual != string(expected) {
+				t.Errorf("expected output\n%s\ngot\n%s\n", expected, actual)
+			}
+
+			if actual := runExampleVM(path); actual != string(expected) {
+				t.Errorf("expected vm output\n%s\ngot\n%s\n", expected, actual)
+			}
+		})
+	}
+}
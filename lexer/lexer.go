@@ -1,25 +1,51 @@
 package lexer
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/MarkyMan4/yetti/token"
 )
 
+// problem found while reading the input, e.g. an illegal character or an invalid escape sequence
+type LexError struct {
+	Pos     token.Position
+	Message string
+}
+
 type Lexer struct {
 	curPos  int
 	readPos int
 	curChar rune
 	chars   []rune
+	line    int // line and column of curChar
+	col     int
+	Errors  []*LexError
 }
 
 func NewLexer(input string) *Lexer {
-	l := &Lexer{chars: []rune(input)}
+	return NewLexerAt(input, token.Position{Line: 1, Col: 1})
+}
+
+// lexer for input that starts part way through a file, e.g. an expression embedded in a string
+// so that token positions are relative to the start of the file
+func NewLexerAt(input string, pos token.Position) *Lexer {
+	l := &Lexer{chars: []rune(input), line: pos.Line, col: pos.Col - 1}
 	l.nextChar()
 	return l
 }
 
 func (l *Lexer) nextChar() {
+	if l.curChar == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+
 	if l.readPos >= len(l.chars) {
 		l.curChar = rune(0)
 	} else {
@@ -32,20 +58,31 @@ func (l *Lexer) nextChar() {
 
 // peek ahead one character without increasing curPos or readPos
 func (l *Lexer) peek() rune {
-	var peekedChar rune
+	return l.peekAhead(0)
+}
 
-	if l.readPos >= len(l.chars) {
-		peekedChar = rune(0)
-	} else {
-		peekedChar = l.chars[l.readPos]
+// peek at the character n places after the next one, peekAhead(0) is the same as peek()
+func (l *Lexer) peekAhead(n int) rune {
+	if l.readPos+n >= len(l.chars) {
+		return rune(0)
 	}
 
-	return peekedChar
+	return l.chars[l.readPos+n]
+}
+
+// position of the current character
+func (l *Lexer) pos() token.Position {
+	return token.Position{Line: l.line, Col: l.col}
+}
+
+func (l *Lexer) addError(pos token.Position, format string, args ...interface{}) {
+	l.Errors = append(l.Errors, &LexError{Pos: pos, Message: fmt.Sprintf(format, args...)})
 }
 
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 	l.skipWhitespace()
+	pos := l.pos()
 
 	switch l.curChar {
 	case '+':
@@ -71,7 +108,9 @@ func (l *Lexer) NextToken() token.Token {
 		}
 	case '/':
 		if l.peek() == '/' {
+			// comments are skipped like whitespace
 			l.readToEndOfLine()
+			return l.NextToken()
 		} else if l.peek() == '=' {
 			tok = token.Token{Type: token.DIVEQ, Literal: "/="}
 			l.nextChar()
@@ -85,6 +124,27 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = token.Token{Type: token.ASSIGN, Literal: string(l.curChar)}
 		}
+	case '!':
+		if l.peek() == '=' {
+			tok = token.Token{Type: token.NEQ, Literal: "!="}
+			l.nextChar()
+		} else {
+			tok = token.Token{Type: token.BANG, Literal: string(l.curChar)}
+		}
+	case '&':
+		if l.peek() == '&' {
+			tok = token.Token{Type: token.AND, Literal: "&&"}
+			l.nextChar()
+		} else {
+			tok = l.readIllegal()
+		}
+	case '|':
+		if l.peek() == '|' {
+			tok = token.Token{Type: token.OR, Literal: "||"}
+			l.nextChar()
+		} else {
+			tok = l.readIllegal()
+		}
 	case '<':
 		if l.peek() == '=' {
 			tok = token.Token{Type: token.LTE, Literal: "<="}
@@ -115,22 +175,30 @@ func (l *Lexer) NextToken() token.Token {
 		tok = token.Token{Type: token.SEMI, Literal: string(l.curChar)}
 	case ',':
 		tok = token.Token{Type: token.COM, Literal: string(l.curChar)}
+	case ':':
+		tok = token.Token{Type: token.COLON, Literal: string(l.curChar)}
 	case '"':
 		tok = l.readString()
+	case '`':
+		tok = l.readRawString()
 	case '.':
 		tok = token.Token{Type: token.DOT, Literal: string(l.curChar)}
 	case rune(0):
 		tok = token.Token{Type: token.EOF, Literal: ""}
 	default:
-		// read a number or an identifier
+		// read a number or an identifier, anything else isn't part of the language
 		if unicode.IsDigit(l.curChar) {
 			tok = l.readIntOrFloat()
-		} else {
+		} else if isIdentChar(l.curChar) {
 			tok = l.readIdent()
+		} else {
+			tok = l.readIllegal()
 		}
 	}
 
 	l.nextChar()
+	tok.Pos = pos
+
 	return tok
 }
 
@@ -140,9 +208,9 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
-// used for comments
+// used for comments, the last line of the input may not end in a newline
 func (l *Lexer) readToEndOfLine() {
-	for l.curChar != '\n' {
+	for l.curChar != '\n' && l.curChar != rune(0) {
 		l.nextChar()
 	}
 }
@@ -176,13 +244,218 @@ func (l *Lexer) readNumber() string {
 	return numTok
 }
 
+// read a string surrounded by double quotes, or by triple double quotes for a string that spans multiple lines
+// escape sequences such as \n are replaced by the characters they stand for. Strings with embedded
+// expressions, e.g. "n is ${n}", are split into parts and returned as an INTERP_STRING token
 func (l *Lexer) readString() token.Token {
-	strTok := ""
+	start := l.pos()
+	startIdx := l.curPos
+	delim := "\""
+
+	if l.peek() == '"' && l.peekAhead(1) == '"' {
+		delim = "\"\"\""
+		l.nextChar()
+		l.nextChar()
+	}
+
+	var sb strings.Builder
+	var parts []token.StringPart
+	l.nextChar()
+
+	for !l.atDelimiter(delim) {
+		// only triple quoted strings can contain newlines
+		if l.curChar == rune(0) || (l.curChar == '\n' && delim == "\"") {
+			l.addError(start, "unterminated string")
+			return token.Token{Type: token.ILLEGAL, Literal: delim + sb.String()}
+		}
+
+		if l.curChar == '\\' {
+			l.readEscape(&sb)
+		} else if l.curChar == '$' && l.peek() == '{' {
+			if sb.Len() > 0 {
+				parts = append(parts, token.StringPart{Text: sb.String()})
+				sb.Reset()
+			}
+
+			part, ok := l.readInterpolation(delim != "\"")
+			if !ok {
+				l.addError(start, "unterminated string")
+				return token.Token{Type: token.ILLEGAL, Literal: string(l.chars[startIdx:l.curPos])}
+			}
+
+			// empty expressions have already been reported
+			if part.Text != "" {
+				parts = append(parts, part)
+			}
+		} else {
+			sb.WriteRune(l.curChar)
+		}
+
+		l.nextChar()
+	}
+
+	// leave curChar on the last quote of the closing delimiter
+	for i := 1; i < len(delim); i++ {
+		l.nextChar()
+	}
+
+	if parts == nil {
+		return token.Token{Type: token.STRING, Literal: sb.String()}
+	}
+
+	if sb.Len() > 0 {
+		parts = append(parts, token.StringPart{Text: sb.String()})
+	}
+
+	return token.Token{Type: token.INTERP_STRING, Literal: string(l.chars[startIdx : l.curPos+1]), Parts: parts}
+}
 
-	// current token is ", read past this and then read until another " is seen
+// read the source of an expression embedded in a string, curChar is the $ and is left on the closing brace
+// the parser lexes and parses the source separately. Returns false if the end of the string is reached first
+func (l *Lexer) readInterpolation(multiline bool) (token.StringPart, bool) {
 	l.nextChar()
+	part := token.StringPart{IsExpr: true, Pos: token.Position{Line: l.line, Col: l.col + 1}}
+	src := ""
+	depth := 0
+
+	for {
+		l.nextChar()
+
+		switch l.curChar {
+		case rune(0):
+			return part, false
+		case '\n':
+			if !multiline {
+				return part, false
+			}
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				if strings.TrimSpace(src) == "" {
+					l.addError(part.Pos, "empty expression in string interpolation")
+				}
+
+				part.Text = src
+				return part, true
+			}
+
+			depth--
+		case '"':
+			// strings inside the expression, e.g. "${m["key"]}", are copied over up to their closing quote
+			src += string(l.curChar)
+			l.nextChar()
+
+			for l.curChar != '"' {
+				if l.curChar == rune(0) || l.curChar == '\n' {
+					return part, false
+				}
+
+				if l.curChar == '\\' {
+					src += string(l.curChar)
+					l.nextChar()
+				}
+
+				src += string(l.curChar)
+				l.nextChar()
+			}
+		}
+
+		src += string(l.curChar)
+	}
+}
+
+func (l *Lexer) atDelimiter(delim string) bool {
+	if delim == "\"" {
+		return l.curChar == '"'
+	}
+
+	return l.curChar == '"' && l.peek() == '"' && l.peekAhead(1) == '"'
+}
+
+// read an escape sequence, curChar is the backslash and is left on the last character of the sequence
+// invalid escapes are reported and kept as they are written
+func (l *Lexer) readEscape(sb *strings.Builder) {
+	start := l.pos()
+
+	switch l.peek() {
+	case 'n':
+		sb.WriteRune('\n')
+	case 't':
+		sb.WriteRune('\t')
+	case 'r':
+		sb.WriteRune('\r')
+	case '\\':
+		sb.WriteRune('\\')
+	case '"':
+		sb.WriteRune('"')
+	case '$':
+		sb.WriteRune('$')
+	case 'u':
+		l.nextChar()
+		l.readUnicodeEscape(sb, start)
+		return
+	case rune(0):
+		// let the caller report the unterminated string
+		sb.WriteRune('\\')
+		return
+	case '\n':
+		l.addError(start, "invalid escape sequence at end of line")
+		sb.WriteRune('\\')
+		return
+	default:
+		l.addError(start, "invalid escape sequence \\%c", l.peek())
+		sb.WriteRune('\\')
+		sb.WriteRune(l.peek())
+	}
+
+	l.nextChar()
+}
+
+// read an escape for a unicode code point written in hex, e.g. \u{1F600}
+// curChar is the u and is left on the closing brace
+func (l *Lexer) readUnicodeEscape(sb *strings.Builder, start token.Position) {
+	if l.peek() != '{' {
+		l.addError(start, "invalid unicode escape, expected \\u{...}")
+		return
+	}
+
+	l.nextChar()
+	hex := ""
+
+	for l.peek() != '}' && l.peek() != '"' && l.peek() != '\n' && l.peek() != rune(0) {
+		l.nextChar()
+		hex += string(l.curChar)
+	}
+
+	if l.peek() != '}' {
+		l.addError(start, "invalid unicode escape, expected \\u{...}")
+		return
+	}
+
+	l.nextChar()
+	val, err := strconv.ParseUint(hex, 16, 32)
+
+	if err != nil || len(hex) > 6 || !utf8.ValidRune(rune(val)) {
+		l.addError(start, "invalid unicode escape \\u{%s}", hex)
+		return
+	}
+
+	sb.WriteRune(rune(val))
+}
+
+// read a string surrounded by backticks, nothing inside is escaped so these are handy for regexes and windows paths
+func (l *Lexer) readRawString() token.Token {
+	start := l.pos()
+	strTok := ""
+	l.nextChar()
+
+	for l.curChar != '`' {
+		if l.curChar == rune(0) {
+			l.addError(start, "unterminated raw string")
+			return token.Token{Type: token.ILLEGAL, Literal: "`" + strTok}
+		}
 
-	for l.curChar != '"' && l.curChar != rune(0) {
 		strTok += string(l.curChar)
 		l.nextChar()
 	}
@@ -190,10 +463,17 @@ func (l *Lexer) readString() token.Token {
 	return token.Token{Type: token.STRING, Literal: strTok}
 }
 
+// a character that isn't part of the language
+func (l *Lexer) readIllegal() token.Token {
+	l.addError(l.pos(), "illegal character %c", l.curChar)
+
+	return token.Token{Type: token.ILLEGAL, Literal: string(l.curChar)}
+}
+
 func (l *Lexer) readIdent() token.Token {
 	literal := string(l.curChar)
 
-	for unicode.IsLetter(l.peek()) || unicode.IsDigit(l.peek()) {
+	for isIdentChar(l.peek()) || unicode.IsDigit(l.peek()) {
 		l.nextChar()
 		literal += string(l.curChar)
 	}
@@ -206,3 +486,8 @@ func (l *Lexer) readIdent() token.Token {
 
 	return token.Token{Type: tokType, Literal: literal}
 }
+
+// identifiers start with a letter or underscore, digits are also allowed after the first character
+func isIdentChar(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
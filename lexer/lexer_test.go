@@ -124,3 +124,219 @@ func TestReturnStatement(t *testing.T) {
 		fmt.Println(tokens[i])
 	}
 }
+
+func TestLogicalOperators(t *testing.T) {
+	input := "!a && b || c != d"
+	expected := []string{token.BANG, token.IDENT, token.AND, token.IDENT, token.OR, token.IDENT, token.NEQ, token.IDENT, token.EOF}
+	lex := NewLexer(input)
+
+	for i := range expected {
+		tok := lex.NextToken()
+
+		if tok.Type != expected[i] {
+			t.Fatalf("expected token %d to be %s but got %s\n", i, expected[i], tok.Type)
+		}
+	}
+}
+
+func TestNull(t *testing.T) {
+	input := "var x = null; nullable = x;"
+	expected := []string{token.VAR, token.IDENT, token.ASSIGN, token.NULL, token.SEMI, token.IDENT, token.ASSIGN, token.IDENT, token.SEMI, token.EOF}
+	lex := NewLexer(input)
+
+	for i := range expected {
+		tok := lex.NextToken()
+
+		if tok.Type != expected[i] {
+			t.Fatalf("expected token %d to be %s but got %s\n", i, expected[i], tok.Type)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	input := "var x = 5;\n\tif(x >= 10) {\n    print(\"big\");\n}"
+	lex := NewLexer(input)
+
+	expected := []struct {
+		literal string
+		line    int
+		col     int
+	}{
+		{"var", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"if", 2, 2},
+		{"(", 2, 4},
+		{"x", 2, 5},
+		{">=", 2, 7},
+		{"10", 2, 10},
+		{")", 2, 12},
+		{"{", 2, 14},
+		{"print", 3, 5},
+		{"(", 3, 10},
+		{"big", 3, 11},
+		{")", 3, 16},
+		{";", 3, 17},
+		{"}", 4, 1},
+	}
+
+	for _, e := range expected {
+		tok := lex.NextToken()
+
+		if tok.Literal != e.literal || tok.Pos.Line != e.line || tok.Pos.Col != e.col {
+			t.Fatalf("expected %s at %d:%d but got %s at %s\n", e.literal, e.line, e.col, tok.Literal, tok.Pos)
+		}
+	}
+}
+
+func TestCommentsSkipped(t *testing.T) {
+	lex := NewLexer("var x = 1; // comment\n// another comment\nx += 1;\n")
+	expected := []string{token.VAR, token.IDENT, token.ASSIGN, token.INT, token.SEMI, token.IDENT, token.PLUSEQ, token.INT, token.SEMI, token.EOF}
+
+	for _, tokType := range expected {
+		tok := lex.NextToken()
+
+		if tok.Type != tokType {
+			t.Fatalf("expected token of type %s but got %s\n", tokType, tok.Type)
+		}
+	}
+}
+
+func TestIllegalTokens(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"x @ y", []token.Token{{Type: token.IDENT, Literal: "x"}, {Type: token.ILLEGAL, Literal: "@"}, {Type: token.IDENT, Literal: "y"}}},
+		{"a & b", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.ILLEGAL, Literal: "&"}, {Type: token.IDENT, Literal: "b"}}},
+		{"a | b", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.ILLEGAL, Literal: "|"}, {Type: token.IDENT, Literal: "b"}}},
+		{"5 % 2", []token.Token{{Type: token.INT, Literal: "5"}, {Type: token.ILLEGAL, Literal: "%"}, {Type: token.INT, Literal: "2"}}},
+		{"var s = \"abc", []token.Token{{Type: token.VAR, Literal: "var"}, {Type: token.IDENT, Literal: "s"}, {Type: token.ASSIGN, Literal: "="}, {Type: token.ILLEGAL, Literal: "\"abc"}}},
+		{"var my_var = _x2;", []token.Token{{Type: token.VAR, Literal: "var"}, {Type: token.IDENT, Literal: "my_var"}, {Type: token.ASSIGN, Literal: "="}, {Type: token.IDENT, Literal: "_x2"}, {Type: token.SEMI, Literal: ";"}}},
+		{"x; // comment at the end of the file", []token.Token{{Type: token.IDENT, Literal: "x"}, {Type: token.SEMI, Literal: ";"}}},
+	}
+
+	for _, tt := range tests {
+		lex := NewLexer(tt.input)
+
+		for _, expected := range tt.expected {
+			tok := lex.NextToken()
+
+			if tok.Type != expected.Type || tok.Literal != expected.Literal {
+				t.Fatalf("lexing %s: expected %s %s but got %s %s\n", tt.input, expected.Type, expected.Literal, tok.Type, tok.Literal)
+			}
+		}
+
+		// the lexer keeps returning EOF once the input is used up
+		for i := 0; i < 3; i++ {
+			if tok := lex.NextToken(); tok.Type != token.EOF {
+				t.Fatalf("lexing %s: expected EOF but got %s %s\n", tt.input, tok.Type, tok.Literal)
+			}
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"plain"`, "plain"},
+		{`"a\nb\tc\rd"`, "a\nb\tc\rd"},
+		{`"quote: \"hi\""`, `quote: "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"\u{e9}\u{1F600}"`, "é😀"},
+		{"`C:\\new\\dir`", `C:\new\dir`},
+		{"`multi\nline`", "multi\nline"},
+		{`""""""`, ""},
+		{"\"\"\"line one\nline \"two\"\\n\"\"\"", "line one\nline \"two\"\n"},
+	}
+
+	for _, tt := range tests {
+		lex := NewLexer(tt.input)
+		tok := lex.NextToken()
+
+		if tok.Type != token.STRING || tok.Literal != tt.expected {
+			t.Fatalf("lexing %s: expected string %q but got %s %q\n", tt.input, tt.expected, tok.Type, tok.Literal)
+		}
+
+		if len(lex.Errors) > 0 {
+			t.Fatalf("lexing %s: unexpected error %s\n", tt.input, lex.Errors[0].Message)
+		}
+
+		if tok = lex.NextToken(); tok.Type != token.EOF {
+			t.Fatalf("lexing %s: expected EOF but got %s %s\n", tt.input, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"bad \q"`, "1:6: invalid escape sequence \\q"},
+		{`"\u{110000}"`, "1:2: invalid unicode escape \\u{110000}"},
+		{`"\u12"`, "1:2: invalid unicode escape, expected \\u{...}"},
+		{"\"no newlines\nin here\"", "1:1: unterminated string"},
+		{"x = \"\"\"never closed", "1:5: unterminated string"},
+		{"`never closed", "1:1: unterminated raw string"},
+	}
+
+	for _, tt := range tests {
+		lex := NewLexer(tt.input)
+
+		for tok := lex.NextToken(); tok.Type != token.EOF; tok = lex.NextToken() {
+		}
+
+		if len(lex.Errors) == 0 {
+			t.Fatalf("lexing %s: expected an error\n", tt.input)
+		}
+
+		err := lex.Errors[0]
+		if fmt.Sprintf("%s: %s", err.Pos, err.Message) != tt.expected {
+			t.Fatalf("lexing %s: expected error %s but got %s: %s\n", tt.input, tt.expected, err.Pos, err.Message)
+		}
+	}
+}
+
+func TestInterpolatedString(t *testing.T) {
+	lex := NewLexer(`x = "n: ${n + 1}, key: ${m["}"]}!";`)
+	lex.NextToken()
+	lex.NextToken()
+	tok := lex.NextToken()
+
+	if tok.Type != token.INTERP_STRING {
+		t.Fatalf("expected an interpolated string but got %s %s\n", tok.Type, tok.Literal)
+	}
+
+	expected := []token.StringPart{
+		{Text: "n: "},
+		{Text: "n + 1", IsExpr: true, Pos: token.Position{Line: 1, Col: 11}},
+		{Text: ", key: "},
+		{Text: `m["}"]`, IsExpr: true, Pos: token.Position{Line: 1, Col: 26}},
+		{Text: "!"},
+	}
+
+	if len(tok.Parts) != len(expected) {
+		t.Fatalf("expected %d parts but got %d\n", len(expected), len(tok.Parts))
+	}
+
+	for i := range expected {
+		if tok.Parts[i] != expected[i] {
+			t.Fatalf("expected part %v but got %v\n", expected[i], tok.Parts[i])
+		}
+	}
+
+	if tok = lex.NextToken(); tok.Type != token.SEMI {
+		t.Fatalf("expected ; after the string but got %s %s\n", tok.Type, tok.Literal)
+	}
+
+	// escaped dollar signs aren't interpolated
+	lex = NewLexer(`"cost: \${n}"`)
+	if tok = lex.NextToken(); tok.Type != token.STRING || tok.Literal != "cost: ${n}" {
+		t.Fatalf("expected plain string but got %s %s\n", tok.Type, tok.Literal)
+	}
+}
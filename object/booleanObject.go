@@ -13,3 +13,11 @@ func (i *BooleanObject) Type() string {
 func (i *BooleanObject) ToString() string {
 	return fmt.Sprint(i.Value)
 }
+
+func (i *BooleanObject) HashKey() HashKey {
+	if i.Value {
+		return HashKey{Type: BOOLEAN_OBJ, Value: 1}
+	}
+
+	return HashKey{Type: BOOLEAN_OBJ, Value: 0}
+}
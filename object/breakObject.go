@@ -0,0 +1,12 @@
+package object
+
+// signals that the innermost loop should stop
+type BreakObject struct{}
+
+func (b *BreakObject) Type() string {
+	return BREAK_OBJ
+}
+
+func (b *BreakObject) ToString() string {
+	return "break"
+}
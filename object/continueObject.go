@@ -0,0 +1,12 @@
+package object
+
+// signals that the innermost loop should skip to its next iteration
+type ContinueObject struct{}
+
+func (c *ContinueObject) Type() string {
+	return CONTINUE_OBJ
+}
+
+func (c *ContinueObject) ToString() string {
+	return "continue"
+}
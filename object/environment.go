@@ -3,6 +3,9 @@ package object
 type Environment struct {
 	definitions map[string]Object
 	parent      *Environment
+	file        string   // path of the file being evaluated, set on the root environment and on function calls
+	hasFile     bool     // whether file was set, programs that aren't from a file have an empty path
+	runtime     *Runtime // only set on the root environment
 }
 
 func NewEnvironment() *Environment {
@@ -61,3 +64,42 @@ func (e *Environment) GetEnvMap() map[string]Object {
 func (e *Environment) GetParentEnv() *Environment {
 	return e.parent
 }
+
+// set the path of the file that is evaluated in this environment, imports are resolved relative to it
+func (e *Environment) SetFile(file string) {
+	e.file = file
+	e.hasFile = true
+}
+
+// get the path of the file being evaluated. This is stored on the root environment, and on the environment
+// of each function call since a function can be called after another file has been run in the same root
+func (e *Environment) File() string {
+	env := e
+
+	for !env.hasFile && env.parent != nil {
+		env = env.parent
+	}
+
+	return env.file
+}
+
+// set the runtime for programs evaluated in this environment
+func (e *Environment) SetRuntime(rt *Runtime) {
+	e.runtime = rt
+}
+
+// get the runtime, this is stored on the root environment. If one was never set, a runtime using
+// the standard streams is created the first time it's needed.
+func (e *Environment) Runtime() *Runtime {
+	env := e
+
+	for env.parent != nil {
+		env = env.parent
+	}
+
+	if env.runtime == nil {
+		env.runtime = DefaultRuntime()
+	}
+
+	return env.runtime
+}
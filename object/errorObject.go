@@ -1,13 +1,53 @@
 package object
 
+import (
+	"fmt"
+
+	"github.com/MarkyMan4/yetti/token"
+)
+
+// kinds of errors raised by the interpreter, scripts can also throw errors with their own kind
+const (
+	ERROR           = "Error"
+	TYPE_ERROR      = "TypeError"
+	NAME_ERROR      = "NameError"
+	INDEX_ERROR     = "IndexError"
+	KEY_ERROR       = "KeyError"
+	ARGUMENT_ERROR  = "ArgumentError"
+	VALUE_ERROR     = "ValueError"
+	IO_ERROR        = "IOError"
+	IMPORT_ERROR    = "ImportError"
+	RECURSION_ERROR = "RecursionError"
+
+	// errors from the filesystem that scripts commonly want to handle separately, other errors are IOErrors
+	FILE_NOT_FOUND_ERROR = "FileNotFoundError"
+	FILE_EXISTS_ERROR    = "FileExistsError"
+	PERMISSION_ERROR     = "PermissionError"
+)
+
+// runtime error, this is passed up through the evaluator until it is caught or ends the program
 type ErrorObject struct {
+	Kind    string
 	Message string
+	File    string         // file the error was raised in, empty if the code didn't come from a file
+	Pos     token.Position // where in the source the error was raised, set by the evaluator
+	Exit    bool           // raised by exit(), this can't be caught and ends the program with Code as its status
+	Code    int
+}
+
+func NewError(kind string, format string, a ...interface{}) *ErrorObject {
+	return &ErrorObject{Kind: kind, Message: fmt.Sprintf(format, a...)}
+}
+
+// exiting is passed up through the evaluator the same way as an error, so everything running is stopped
+func NewExit(code int) *ErrorObject {
+	return &ErrorObject{Kind: "Exit", Message: fmt.Sprintf("exit status %d", code), Exit: true, Code: code}
 }
 
 func (i *ErrorObject) Type() string {
-	return STRING_OBJ
+	return ERROR_OBJ
 }
 
 func (i *ErrorObject) ToString() string {
-	return i.Message
+	return fmt.Sprintf("%s: %s", i.Kind, i.Message)
 }
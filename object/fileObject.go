@@ -1,9 +1,56 @@
 package object
 
-import "fmt"
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
 
+// returned by OpenFile for a mode that isn't in fileModes
+var ErrUnknownFileMode = errors.New("unknown mode")
+
+// flags used to open a file in each mode
+var fileModes = map[string]int{
+	"r":  os.O_RDONLY,
+	"w":  os.O_WRONLY | os.O_CREATE | os.O_TRUNC,
+	"a":  os.O_WRONLY | os.O_CREATE | os.O_APPEND,
+	"r+": os.O_RDWR,
+	"w+": os.O_RDWR | os.O_CREATE | os.O_TRUNC,
+	"a+": os.O_RDWR | os.O_CREATE | os.O_APPEND,
+}
+
+// handle to an open file. Reads and writes are buffered, so the reader and writer are kept in step
+// with the position of the file whenever the handle switches between reading, writing and seeking.
 type FileObject struct {
 	FileName string
+	Mode     string
+	file     *os.File
+	reader   *bufio.Reader
+	writer   *bufio.Writer
+	closed   bool
+}
+
+// open a file in one of the modes r, w, a, r+, w+ or a+
+func OpenFile(name string, mode string) (*FileObject, error) {
+	flag, ok := fileModes[mode]
+	if !ok {
+		return nil, fmt.Errorf("%w %s, expected one of r, w, a, r+, w+ or a+", ErrUnknownFileMode, mode)
+	}
+
+	file, err := os.OpenFile(name, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileObject{
+		FileName: name,
+		Mode:     mode,
+		file:     file,
+		reader:   bufio.NewReader(file),
+		writer:   bufio.NewWriter(file),
+	}, nil
 }
 
 func (f *FileObject) Type() string {
@@ -13,3 +60,127 @@ func (f *FileObject) Type() string {
 func (f *FileObject) ToString() string {
 	return fmt.Sprintf("file object %s", f.FileName)
 }
+
+func (f *FileObject) Closed() bool {
+	return f.closed
+}
+
+// check the file is open, and was opened in a mode that allows reading or writing
+func (f *FileObject) check(reading bool) error {
+	if f.closed {
+		return fmt.Errorf("file %s is closed", f.FileName)
+	}
+
+	// files opened with r can only be read, files opened with w or a can only be written
+	if reading && (f.Mode == "w" || f.Mode == "a") {
+		return fmt.Errorf("file %s is not open for reading", f.FileName)
+	}
+
+	if !reading && f.Mode == "r" {
+		return fmt.Errorf("file %s is not open for writing", f.FileName)
+	}
+
+	return nil
+}
+
+// write anything still buffered, and move the file back over input that was read ahead but not used,
+// so the position of the file is where the program thinks it is
+func (f *FileObject) sync() error {
+	if err := f.writer.Flush(); err != nil {
+		return err
+	}
+
+	if buffered := f.reader.Buffered(); buffered > 0 {
+		if _, err := f.file.Seek(-int64(buffered), io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+
+	f.reader.Reset(f.file)
+
+	return nil
+}
+
+// read the next line without its line ending, ok is false at the end of the file
+func (f *FileObject) ReadLine() (line string, ok bool, err error) {
+	if err := f.check(true); err != nil {
+		return "", false, err
+	}
+
+	if err := f.writer.Flush(); err != nil {
+		return "", false, err
+	}
+
+	return readLine(f.reader)
+}
+
+// read from the current position to the end of the file
+func (f *FileObject) ReadAll() (string, error) {
+	if err := f.check(true); err != nil {
+		return "", err
+	}
+
+	if err := f.writer.Flush(); err != nil {
+		return "", err
+	}
+
+	content, err := io.ReadAll(f.reader)
+
+	return string(content), err
+}
+
+func (f *FileObject) Write(s string) error {
+	if err := f.check(false); err != nil {
+		return err
+	}
+
+	// writes go where reading stopped, not to the end of what was read ahead
+	if f.reader.Buffered() > 0 {
+		if err := f.sync(); err != nil {
+			return err
+		}
+	}
+
+	_, err := f.writer.WriteString(s)
+
+	return err
+}
+
+// move to offset relative to the start of the file, the current position or the end of the file,
+// depending on whence being io.SeekStart, io.SeekCurrent or io.SeekEnd. Returns the new position.
+func (f *FileObject) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, fmt.Errorf("file %s is closed", f.FileName)
+	}
+
+	if err := f.sync(); err != nil {
+		return 0, err
+	}
+
+	return f.file.Seek(offset, whence)
+}
+
+func (f *FileObject) Flush() error {
+	if err := f.check(false); err != nil {
+		return err
+	}
+
+	return f.writer.Flush()
+}
+
+// flush anything that hasn't been written and close the file, closing a file that is already closed does nothing
+func (f *FileObject) Close() error {
+	if f.closed {
+		return nil
+	}
+
+	f.closed = true
+
+	err := f.writer.Flush()
+
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,110 @@
+package object
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileReadAndWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.txt")
+
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenFile(path, "r+")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line, ok, err := f.ReadLine()
+	if err != nil || !ok || line != "one" {
+		t.Fatalf("expected to read one, got %q, %v, %v\n", line, ok, err)
+	}
+
+	// the write goes after the first line even though the rest of the file was read ahead
+	if err := f.Write("TWO\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	rest, err := f.ReadAll()
+	if err != nil || rest != "three\n" {
+		t.Fatalf("expected the rest of the file to be three, got %q, %v\n", rest, err)
+	}
+
+	if pos, err := f.Seek(-6, io.SeekEnd); err != nil || pos != 8 {
+		t.Fatalf("expected to seek to 8, got %d, %v\n", pos, err)
+	}
+
+	if line, _, _ := f.ReadLine(); line != "three" {
+		t.Fatalf("expected to read three after seeking, got %q\n", line)
+	}
+
+	if _, ok, _ := f.ReadLine(); ok {
+		t.Fatal("expected no lines left")
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("expected closing twice to do nothing, got %v\n", err)
+	}
+
+	if _, _, err := f.ReadLine(); err == nil {
+		t.Fatal("expected an error reading a closed file")
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "one\nTWO\nthree\n" {
+		t.Fatalf("unexpected file content %q\n", content)
+	}
+}
+
+func TestFileModes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.txt")
+
+	if _, err := OpenFile(path, "r"); err == nil {
+		t.Fatal("expected an error opening a file that doesn't exist for reading")
+	}
+
+	if _, err := OpenFile(path, "x"); !errors.Is(err, ErrUnknownFileMode) {
+		t.Fatal("expected an error opening a file with an unknown mode")
+	}
+
+	for _, mode := range []string{"w", "a"} {
+		f, err := OpenFile(path, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := f.Write(mode); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := f.ReadAll(); err == nil {
+			t.Fatalf("expected an error reading a file opened with %s\n", mode)
+		}
+
+		f.Close()
+	}
+
+	f, err := OpenFile(path, "r")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	if content, _ := f.ReadAll(); content != "wa" {
+		t.Fatalf("expected the file to contain wa, got %q\n", content)
+	}
+
+	if err := f.Write("x"); err == nil {
+		t.Fatal("expected an error writing a file opened with r")
+	}
+}
@@ -1,6 +1,9 @@
 package object
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+)
 
 type FloatObject struct {
 	Value float64
@@ -13,3 +16,12 @@ func (i *FloatObject) Type() string {
 func (i *FloatObject) ToString() string {
 	return fmt.Sprint(i.Value)
 }
+
+// whole numbers use the same key as the equivalent integer since 1 == 1.0
+func (i *FloatObject) HashKey() HashKey {
+	if i.Value == math.Trunc(i.Value) && i.Value >= math.MinInt64 && i.Value < math.MaxInt64 {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(i.Value))}
+	}
+
+	return HashKey{Type: FLOAT_OBJ, Value: math.Float64bits(i.Value)}
+}
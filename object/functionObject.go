@@ -7,6 +7,8 @@ import (
 type FunctionObject struct {
 	Args       []string
 	Statements []ast.Statement
+	Env        *Environment // environment the function was defined in, used as the parent scope when it's called
+	File       string       // file the function was defined in, errors and imports in its body are relative to it
 }
 
 func (f *FunctionObject) Type() string {
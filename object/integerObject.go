@@ -13,3 +13,7 @@ func (i *IntegerObject) Type() string {
 func (i *IntegerObject) ToString() string {
 	return fmt.Sprint(i.Value)
 }
+
+func (i *IntegerObject) HashKey() HashKey {
+	return HashKey{Type: INTEGER_OBJ, Value: uint64(i.Value)}
+}
@@ -0,0 +1,15 @@
+package object
+
+// items produced one at a time as a for-in loop asks for them, e.g. lines of input, so the loop can
+// start before everything has been read. Next returns false once there are no items left.
+type IteratorObject struct {
+	Next func() (Object, bool, *ErrorObject)
+}
+
+func (i *IteratorObject) Type() string {
+	return ITERATOR_OBJ
+}
+
+func (i *IteratorObject) ToString() string {
+	return "iterator"
+}
@@ -0,0 +1,88 @@
+package object
+
+import "fmt"
+
+// key used to store an object in a map, objects that are equal have the same key
+type HashKey struct {
+	Type  string
+	Value uint64 // used for integers, floats and booleans
+	Str   string // strings are stored in full so different strings never share a key
+}
+
+// objects that can be used as keys in a map
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+type MapPair struct {
+	Key   Object
+	Value Object
+}
+
+type MapObject struct {
+	Pairs map[HashKey]MapPair
+	Keys  []HashKey // keys in the order they were inserted
+}
+
+func NewMapObject() *MapObject {
+	return &MapObject{Pairs: make(map[HashKey]MapPair)}
+}
+
+func (m *MapObject) Type() string {
+	return MAP_OBJ
+}
+
+func (m *MapObject) ToString() string {
+	mapStr := ""
+
+	for i, hashKey := range m.Keys {
+		pair := m.Pairs[hashKey]
+		mapStr += fmt.Sprintf("%s:%s", pair.Key.ToString(), pair.Value.ToString())
+
+		if i < len(m.Keys)-1 {
+			mapStr += ","
+		}
+	}
+
+	return fmt.Sprintf("{%s}", mapStr)
+}
+
+func (m *MapObject) Get(key Hashable) (Object, bool) {
+	pair, ok := m.Pairs[key.HashKey()]
+	if !ok {
+		return nil, false
+	}
+
+	return pair.Value, true
+}
+
+func (m *MapObject) Set(key Hashable, val Object) {
+	hashKey := key.HashKey()
+
+	if _, ok := m.Pairs[hashKey]; !ok {
+		m.Keys = append(m.Keys, hashKey)
+	}
+
+	m.Pairs[hashKey] = MapPair{Key: key, Value: val}
+}
+
+// remove a key from the map, returns false if the key was not in the map
+func (m *MapObject) Delete(key Hashable) bool {
+	hashKey := key.HashKey()
+
+	if _, ok := m.Pairs[hashKey]; !ok {
+		return false
+	}
+
+	delete(m.Pairs, hashKey)
+
+	for i := range m.Keys {
+		if m.Keys[i] == hashKey {
+			m.Keys = append(m.Keys[:i], m.Keys[i+1:]...)
+			break
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,52 @@
+package object
+
+import "testing"
+
+func TestHashKeys(t *testing.T) {
+	if (&StringObject{Value: "a"}).HashKey() != (&StringObject{Value: "a"}).HashKey() {
+		t.Fatal("expected equal strings to have the same key")
+	}
+
+	if (&StringObject{Value: "a"}).HashKey() == (&StringObject{Value: "b"}).HashKey() {
+		t.Fatal("expected different strings to have different keys")
+	}
+
+	if (&IntegerObject{Value: 1}).HashKey() != (&FloatObject{Value: 1.0}).HashKey() {
+		t.Fatal("expected 1 and 1.0 to have the same key")
+	}
+
+	if (&FloatObject{Value: 1.5}).HashKey() == (&FloatObject{Value: 2.5}).HashKey() {
+		t.Fatal("expected different floats to have different keys")
+	}
+
+	if (&IntegerObject{Value: 1}).HashKey() == (&BooleanObject{Value: true}).HashKey() {
+		t.Fatal("expected 1 and true to have different keys")
+	}
+
+	if (&StringObject{Value: "1"}).HashKey() == (&IntegerObject{Value: 1}).HashKey() {
+		t.Fatal("expected \"1\" and 1 to have different keys")
+	}
+}
+
+func TestMapObject(t *testing.T) {
+	m := NewMapObject()
+	m.Set(&StringObject{Value: "b"}, &IntegerObject{Value: 2})
+	m.Set(&StringObject{Value: "a"}, &IntegerObject{Value: 1})
+	m.Set(&StringObject{Value: "b"}, &IntegerObject{Value: 3})
+
+	if m.ToString() != "{b:3,a:1}" {
+		t.Fatalf("expected map to keep insertion order but got %s\n", m.ToString())
+	}
+
+	if !m.Delete(&StringObject{Value: "b"}) {
+		t.Fatal("expected key b to be deleted")
+	}
+
+	if _, ok := m.Get(&StringObject{Value: "b"}); ok {
+		t.Fatal("expected key b to be removed")
+	}
+
+	if m.Delete(&StringObject{Value: "c"}) {
+		t.Fatal("expected deleting a missing key to return false")
+	}
+}
@@ -0,0 +1,22 @@
+package object
+
+import "fmt"
+
+// module created by an import statement, members of the module are its top-level definitions
+type ModuleObject struct {
+	Path    string
+	Members Members
+}
+
+// top-level definitions of a module, for the evaluator this is the environment the module ran in
+type Members interface {
+	Get(ident string) (Object, bool)
+}
+
+func (m *ModuleObject) Type() string {
+	return MODULE_OBJ
+}
+
+func (m *ModuleObject) ToString() string {
+	return fmt.Sprintf("module %s", m.Path)
+}
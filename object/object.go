@@ -8,9 +8,14 @@ const (
 	ERROR_OBJ    = "ERROR"
 	FUNCTION_OBJ = "FUNCTION"
 	ARRAY_OBJ    = "ARRAY"
+	MAP_OBJ      = "MAP"
 	NULL_OBJ     = "NULL"
 	RETURN_OBJ   = "RETURN_OBJ"
 	FILE_OBJ     = "FILE"
+	MODULE_OBJ   = "MODULE"
+	ITERATOR_OBJ = "ITERATOR"
+	BREAK_OBJ    = "BREAK_OBJ"
+	CONTINUE_OBJ = "CONTINUE_OBJ"
 )
 
 type Object interface {
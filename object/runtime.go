@@ -0,0 +1,118 @@
+package object
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// state shared by everything a single interpreter runs, including the modules it imports.
+// Builtins read and write the streams here instead of os.Stdin and os.Stdout, so a host can
+// capture a program's output or run several programs side by side.
+type Runtime struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Args   []string // command-line arguments given to the program, not including the interpreter or the file
+
+	Modules     map[string]*ModuleObject // modules that have already been imported, keyed by absolute path
+	ImportStack []string                 // absolute paths of the modules currently being imported
+	SearchPath  []string                 // directories to search for imports that aren't found relative to the importing file
+
+	MaxCallDepth int // maximum number of nested function calls, deeper recursion raises a RecursionError
+	CallDepth    int // number of function calls currently running
+
+	input *bufio.Reader // buffers Stdin, created the first time input is read
+	files []*FileObject // files opened by the program, closed when it ends
+}
+
+// limit on nested function calls for new runtimes
+const DefaultMaxCallDepth = 1000
+
+func NewRuntime(stdin io.Reader, stdout io.Writer, stderr io.Writer) *Runtime {
+	return &Runtime{
+		Stdin:        stdin,
+		Stdout:       stdout,
+		Stderr:       stderr,
+		Modules:      map[string]*ModuleObject{},
+		MaxCallDepth: DefaultMaxCallDepth,
+	}
+}
+
+// runtime using the standard streams of the process
+func DefaultRuntime() *Runtime {
+	return NewRuntime(os.Stdin, os.Stdout, os.Stderr)
+}
+
+// replace the streams, any input that was buffered from the old stdin is dropped
+func (rt *Runtime) SetIO(stdin io.Reader, stdout io.Writer, stderr io.Writer) {
+	rt.Stdin = stdin
+	rt.Stdout = stdout
+	rt.Stderr = stderr
+	rt.input = nil
+}
+
+// get the reader everything reading from stdin shares. Reading from Stdin directly would skip input
+// that has already been buffered here, so use SetIO to change Stdin once a program has read from it.
+func (rt *Runtime) Input() *bufio.Reader {
+	if rt.input == nil {
+		rt.input = bufio.NewReader(rt.Stdin)
+	}
+
+	return rt.input
+}
+
+// read the next line of input without its line ending, ok is false once there is no input left.
+// The last line is returned even if it doesn't end in a newline.
+func (rt *Runtime) ReadLine() (line string, ok bool, err error) {
+	return readLine(rt.Input())
+}
+
+// read everything left on stdin
+func (rt *Runtime) ReadAll() (string, error) {
+	content, err := io.ReadAll(rt.Input())
+
+	return string(content), err
+}
+
+func readLine(r *bufio.Reader) (line string, ok bool, err error) {
+	line, err = r.ReadString('\n')
+
+	if err == io.EOF && line == "" {
+		return "", false, nil
+	} else if err != nil && err != io.EOF {
+		return "", false, err
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), true, nil
+}
+
+// keep track of a file the program opened, so it can be closed when the program ends
+func (rt *Runtime) AddFile(f *FileObject) {
+	// forget files the program has already closed, so opening files in a loop doesn't keep them all
+	open := rt.files[:0]
+
+	for _, file := range rt.files {
+		if !file.Closed() {
+			open = append(open, file)
+		}
+	}
+
+	rt.files = append(open, f)
+}
+
+// close the files the program left open, returns the first error closing them
+func (rt *Runtime) CloseFiles() error {
+	var err error
+
+	for _, f := range rt.files {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	rt.files = nil
+
+	return err
+}
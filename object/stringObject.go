@@ -11,3 +11,7 @@ func (i *StringObject) Type() string {
 func (i *StringObject) ToString() string {
 	return i.Value
 }
+
+func (i *StringObject) HashKey() HashKey {
+	return HashKey{Type: STRING_OBJ, Str: i.Value}
+}
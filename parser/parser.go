@@ -14,14 +14,58 @@ type (
 	infixParser  func(ast.Expression) ast.Expression
 )
 
+// operator precedences, operators with a higher precedence bind more tightly
+const (
+	_ int = iota
+	LOWEST
+	OR      // ||
+	AND     // &&
+	EQUALS  // == and !=
+	COMPARE // <, <=, >, >=
+	SUM     // + and -
+	PRODUCT // * and /
+	PREFIX  // !x, -x, +x
+	INDEX   // xs[i], f(x) and member access, e.g. s.length()
+)
+
+var precedences = map[string]int{
+	token.OR:     OR,
+	token.AND:    AND,
+	token.EQ:     EQUALS,
+	token.NEQ:    EQUALS,
+	token.LT:     COMPARE,
+	token.LTE:    COMPARE,
+	token.GT:     COMPARE,
+	token.GTE:    COMPARE,
+	token.PLUS:   SUM,
+	token.MINUS:  SUM,
+	token.MULT:   PRODUCT,
+	token.DIVIDE: PRODUCT,
+	token.DOT:    INDEX,
+	token.LBRACK: INDEX,
+	token.LPAREN: INDEX,
+}
+
+// error found while parsing, along with where in the source it happened
+type ParseError struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
 type Parser struct {
 	Lex           *lexer.Lexer
 	prevToken     token.Token
 	curToken      token.Token
 	peekToken     token.Token
-	Errors        []string
+	Errors        []*ParseError
 	prefixParsers map[string]prefixParser
 	infixParsers  map[string]infixParser
+	loopDepth     int // number of loops enclosing the current statement, used to validate break and continue
+	lexErrors     int // number of errors from the lexer that have been added to Errors
 }
 
 func NewParser(l *lexer.Lexer) *Parser {
@@ -34,31 +78,39 @@ func NewParser(l *lexer.Lexer) *Parser {
 
 	// prefix parsers (e.g. an int is a prefix in an expression)
 	p.prefixParsers = map[string]prefixParser{
-		token.INT:     p.parseIntegerLiteral,
-		token.FLOAT:   p.parseFloatLiteral,
-		token.BOOLEAN: p.parseBooleanLiteral,
-		token.STRING:  p.parseStringLiteral,
-		token.IDENT:   p.parseIdent,
-		token.LBRACK:  p.parseArray,
+		token.INT:           p.parseIntegerLiteral,
+		token.FLOAT:         p.parseFloatLiteral,
+		token.BOOLEAN:       p.parseBooleanLiteral,
+		token.NULL:          p.parseNullLiteral,
+		token.STRING:        p.parseStringLiteral,
+		token.INTERP_STRING: p.parseInterpolatedString,
+		token.IDENT:         p.parseIdent,
+		token.LBRACK:        p.parseArray,
+		token.LBRACE:        p.parseMap,
+		token.LPAREN:        p.parseGroupedExpression,
+		token.FUN:           p.parseFunctionLiteral,
+		token.BANG:          p.parsePrefixExpression,
+		token.MINUS:         p.parsePrefixExpression,
+		token.PLUS:          p.parsePrefixExpression,
 	}
 
 	// infix parsers (e.g. +, -, *, /)
 	p.infixParsers = map[string]infixParser{
 		token.PLUS:   p.parseInfixExpression,
-		token.PLUSEQ: p.parseInfixExpression,
 		token.MINUS:  p.parseInfixExpression,
-		token.MINEQ:  p.parseInfixExpression,
 		token.MULT:   p.parseInfixExpression,
-		token.MULTEQ: p.parseInfixExpression,
 		token.DIVIDE: p.parseInfixExpression,
-		token.DIVEQ:  p.parseInfixExpression,
 		token.LT:     p.parseInfixExpression,
 		token.LTE:    p.parseInfixExpression,
 		token.EQ:     p.parseInfixExpression,
+		token.NEQ:    p.parseInfixExpression,
+		token.AND:    p.parseInfixExpression,
+		token.OR:     p.parseInfixExpression,
 		token.GT:     p.parseInfixExpression,
 		token.GTE:    p.parseInfixExpression,
 		token.DOT:    p.parseObjFuncExpression,
 		token.LBRACK: p.parseIndexExpression,
+		token.LPAREN: p.parseCallExpression,
 	}
 
 	return p
@@ -68,55 +120,148 @@ func (p *Parser) nextToken() {
 	p.prevToken = p.curToken
 	p.curToken = p.peekToken
 	p.peekToken = p.Lex.NextToken()
+
+	// report problems from the lexer as soon as they're read, the parser will then fail on
+	// illegal tokens like any other unexpected token
+	for p.lexErrors < len(p.Lex.Errors) {
+		err := p.Lex.Errors[p.lexErrors]
+		p.addError(err.Pos, "%s", err.Message)
+		p.lexErrors++
+	}
 }
 
 func (p *Parser) Parse() *ast.Program {
 	prog := &ast.Program{Statements: []ast.Statement{}}
 
 	for p.curToken.Type != token.EOF {
-		prog.Statements = append(prog.Statements, p.parseStmt())
-		p.nextToken()
-
+		// semicolons may be left over after function calls
 		if p.curToken.Type == token.SEMI {
 			p.nextToken()
+			continue
+		}
+
+		if stmt := p.parseStmt(); stmt != nil {
+			prog.Statements = append(prog.Statements, stmt)
+		} else {
+			p.synchronize()
 		}
+
+		p.nextToken()
 	}
 
 	return prog
 }
 
+// skip the rest of a statement that couldn't be parsed, so parsing can carry on with the next statement
+// and report any other errors. Leaves curToken on the last token of the statement, either a semicolon
+// or the closing brace of its block, or just before the closing brace of the block the statement is in
+func (p *Parser) synchronize() {
+	depth := 0
+
+	for p.curToken.Type != token.EOF {
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+			if depth <= 0 {
+				return
+			}
+		case token.SEMI:
+			if depth == 0 {
+				return
+			}
+		}
+
+		if depth == 0 && (p.peekToken.Type == token.RBRACE || p.peekToken.Type == token.EOF) {
+			return
+		}
+
+		p.nextToken()
+	}
+}
+
+// parse input that is a single expression, optionally followed by a semicolon, e.g. "x + 1"
+// returns nil if the input is anything else so the caller can parse it as a program instead.
+// Input that starts like an expression and doesn't end in a semicolon is taken to be an expression,
+// so if it can't be parsed, nil is returned with the errors left in Errors.
+func (p *Parser) ParseExpressionInput() ast.Expression {
+	// fun starts a function definition rather than a function literal when it's the first token
+	_, startsExpr := p.prefixParsers[p.curToken.Type]
+	startsExpr = startsExpr && p.curToken.Type != token.FUN
+
+	expr := p.parseExpression(LOWEST)
+	if expr == nil || len(p.Errors) > 0 {
+		if !startsExpr || p.endsInSemicolon() {
+			p.Errors = nil
+		}
+
+		return nil
+	}
+
+	if p.curToken.Type != token.SEMI && p.peekToken.Type == token.SEMI {
+		p.nextToken()
+	}
+
+	if p.peekToken.Type != token.EOF {
+		return nil
+	}
+
+	return expr
+}
+
+// skip to the end of the input and check whether its last token is a semicolon
+func (p *Parser) endsInSemicolon() bool {
+	last := p.prevToken
+
+	for p.curToken.Type != token.EOF {
+		last = p.curToken
+		p.nextToken()
+	}
+
+	return last.Type == token.SEMI
+}
+
 func (p *Parser) parseStmt() ast.Statement {
 	switch p.curToken.Type {
 	case token.VAR:
 		return p.parseVarStmt()
 	case token.WHILE:
 		return p.parseWhileStmt()
+	case token.FOR:
+		return p.parseForStmt()
 	case token.IF:
 		return p.parseIfStmt()
 	case token.FUN:
 		return p.parseFunctionDef()
 	case token.RETURN:
 		return p.parseReturnStmt()
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControlStmt()
+	case token.THROW:
+		return p.parseThrowStmt()
+	case token.TRY:
+		return p.parseTryStmt()
+	case token.IMPORT:
+		return p.parseImportStmt()
 	case token.IDENT:
-		// determine how the ident is being used based on the next token
-		switch p.peekToken.Literal {
-		case token.LPAREN:
-			return p.parseFunctionCall()
-		default:
-			return p.parseAssignStmt()
-		}
+		return p.parseIdentStmt()
 	default:
+		p.addError(p.curToken.Pos, "%s. Expected a statement", unexpected(p.curToken))
+
 		return nil
 	}
 }
 
 func (p *Parser) parseVarStmt() ast.Statement {
+	start := p.curToken.Pos
 	if !p.expectNextToken(token.IDENT) {
 		return nil
 	}
 
 	p.nextToken()
 	stmt := &ast.VarStatement{Identifier: p.curToken.Literal}
+	stmt.Start = start
 
 	if !p.expectNextToken(token.ASSIGN) {
 		return nil
@@ -124,7 +269,10 @@ func (p *Parser) parseVarStmt() ast.Statement {
 
 	p.nextToken()
 	p.nextToken()
-	stmt.Value = p.parseExpression()
+	stmt.Value = p.parseExpression(LOWEST)
+	if stmt.Value == nil {
+		return nil
+	}
 
 	// TODO: figure out where cursor should leave off after parsing function call (on semi colon or right paren)
 	if p.curToken.Type != token.SEMI && !p.expectNextToken(token.SEMI) {
@@ -143,21 +291,79 @@ func (p *Parser) expectNextToken(tokType string) bool {
 		return true
 	}
 
-	errMsg := fmt.Sprintf("Expected token %s to be %s, but got %s", p.peekToken, tokType, p.peekToken.Type)
-	p.Errors = append(p.Errors, errMsg)
+	p.addError(p.peekToken.Pos, "Expected next token to be %s, but got %s", tokType, describe(p.peekToken))
 
 	return false
 }
 
-func (p *Parser) parseExpression() ast.Expression {
+// describe a token in an error message, the end of the input has no literal so it's described in words
+func describe(tok token.Token) string {
+	if tok.Type == token.EOF {
+		return "end of input"
+	}
+
+	return tok.Literal
+}
+
+// start of an error message for a token that isn't allowed where it was found
+func unexpected(tok token.Token) string {
+	if tok.Type == token.EOF {
+		return "Unexpected end of input"
+	}
+
+	return "Unexpected token " + tok.Literal
+}
+
+// only the first error at a position is kept, since later ones are usually caused by the first
+func (p *Parser) addError(pos token.Position, format string, args ...interface{}) {
+	for _, err := range p.Errors {
+		if err.Pos == pos {
+			return
+		}
+	}
+
+	p.Errors = append(p.Errors, &ParseError{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// nodes that start with another expression, e.g. x + 1 or xs[0], start where that expression starts
+// tok is the operator, which is used instead if the expression couldn't be parsed
+func startOf(expr ast.Expression, tok token.Token) token.Position {
+	if expr == nil {
+		return tok.Pos
+	}
+
+	return expr.Position()
+}
+
+func (p *Parser) peekPrecedence() int {
+	if prec, ok := precedences[p.peekToken.Type]; ok {
+		return prec
+	}
+
+	return LOWEST
+}
+
+func (p *Parser) curPrecedence() int {
+	if prec, ok := precedences[p.curToken.Type]; ok {
+		return prec
+	}
+
+	return LOWEST
+}
+
+// parse an expression, stopping at the first infix operator that doesn't bind more tightly than precedence
+func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParsers[p.curToken.Type]
 	if prefix == nil {
-		// TODO: do some kind of error here
+		p.addError(p.curToken.Pos, "%s. Expected an expression", unexpected(p.curToken))
+
 		return nil
 	}
 
+	// a part of the expression that couldn't be parsed makes the whole expression invalid,
+	// so nodes are never built with missing children
 	left := prefix()
-	for p.peekToken.Type != token.SEMI {
+	for left != nil && p.peekToken.Type != token.SEMI && precedence < p.peekPrecedence() {
 		infix := p.infixParsers[p.peekToken.Type]
 		if infix == nil {
 			return left
@@ -172,11 +378,11 @@ func (p *Parser) parseExpression() ast.Expression {
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	intLit := &ast.IntegerLiteral{}
+	intLit.Start = p.curToken.Pos
 	val, err := strconv.ParseInt(p.curToken.Literal, 10, 64)
 
 	if err != nil {
-		errMsg := fmt.Sprintf("could not parse %s as type integer", p.curToken.Literal)
-		p.Errors = append(p.Errors, errMsg)
+		p.addError(p.curToken.Pos, "could not parse %s as type integer", p.curToken.Literal)
 		return nil
 	}
 
@@ -187,11 +393,11 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 
 func (p *Parser) parseFloatLiteral() ast.Expression {
 	floatLit := &ast.FloatLiteral{}
+	floatLit.Start = p.curToken.Pos
 	val, err := strconv.ParseFloat(p.curToken.Literal, 64)
 
 	if err != nil {
-		errMsg := fmt.Sprintf("could not parse %s as type float", p.curToken.Literal)
-		p.Errors = append(p.Errors, errMsg)
+		p.addError(p.curToken.Pos, "could not parse %s as type float", p.curToken.Literal)
 		return nil
 	}
 
@@ -202,11 +408,11 @@ func (p *Parser) parseFloatLiteral() ast.Expression {
 
 func (p *Parser) parseBooleanLiteral() ast.Expression {
 	boolLit := &ast.BooleanLiteral{}
+	boolLit.Start = p.curToken.Pos
 	val, err := strconv.ParseBool(p.curToken.Literal)
 
 	if err != nil {
-		errMsg := fmt.Sprintf("could not parse %s as type boolean", p.curToken.Literal)
-		p.Errors = append(p.Errors, errMsg)
+		p.addError(p.curToken.Pos, "could not parse %s as type boolean", p.curToken.Literal)
 		return nil
 	}
 
@@ -215,18 +421,67 @@ func (p *Parser) parseBooleanLiteral() ast.Expression {
 	return boolLit
 }
 
+func (p *Parser) parseNullLiteral() ast.Expression {
+	nullLit := &ast.NullLiteral{}
+	nullLit.Start = p.curToken.Pos
+
+	return nullLit
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
-	return &ast.StringLiteral{Value: p.curToken.Literal}
+	strLit := &ast.StringLiteral{Value: p.curToken.Literal}
+	strLit.Start = p.curToken.Pos
+
+	return strLit
+}
+
+// each expression in an interpolated string is parsed with its own parser, positions still
+// line up with the file since the lexer for the expression starts where the expression does
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{}
+	str.Start = p.curToken.Pos
+
+	for _, part := range p.curToken.Parts {
+		if !part.IsExpr {
+			strLit := &ast.StringLiteral{Value: part.Text}
+			strLit.Start = p.curToken.Pos
+			str.Parts = append(str.Parts, strLit)
+
+			continue
+		}
+
+		exprParser := NewParser(lexer.NewLexerAt(part.Text, part.Pos))
+		expr := exprParser.parseExpression(LOWEST)
+
+		if expr != nil && exprParser.peekToken.Type != token.EOF {
+			exprParser.addError(exprParser.peekToken.Pos, "%s. Expected } to end the embedded expression", unexpected(exprParser.peekToken))
+		}
+
+		for _, err := range exprParser.Errors {
+			p.addError(err.Pos, "%s", err.Message)
+		}
+
+		if len(exprParser.Errors) > 0 {
+			return nil
+		}
+
+		str.Parts = append(str.Parts, expr)
+	}
+
+	return str
 }
 
 // handles parsing variables and function calls
 func (p *Parser) parseIdent() ast.Expression {
 	if p.peekToken.Type == token.LPAREN {
-		res := p.parseFunctionCall().(ast.Expression)
+		res, _ := p.parseFunctionCall().(ast.Expression)
 		return res
 	}
 
-	return &ast.IdentifierExpression{Value: p.curToken.Literal}
+	ident := &ast.IdentifierExpression{Value: p.curToken.Literal}
+	ident.Start = p.curToken.Pos
+
+	return ident
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
@@ -234,15 +489,49 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Op:   p.curToken.Literal,
 		Left: left,
 	}
+	expr.Start = startOf(left, p.curToken)
 
+	// the right side stops at operators of the same precedence so operators are left associative
+	precedence := p.curPrecedence()
 	p.nextToken()
-	expr.Right = p.parseExpression()
+	expr.Right = p.parseExpression(precedence)
+	if expr.Right == nil {
+		return nil
+	}
+
+	return expr
+}
+
+// expression wrapped in parentheses, e.g. (1 + 2) * 3
+func (p *Parser) parseGroupedExpression() ast.Expression {
+	p.nextToken()
+	expr := p.parseExpression(LOWEST)
+
+	if expr == nil || !p.expectNextToken(token.RPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	return expr
+}
+
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	expr := &ast.PrefixExpression{Op: p.curToken.Literal}
+	expr.Start = p.curToken.Pos
+
+	p.nextToken()
+	expr.Right = p.parseExpression(PREFIX)
+	if expr.Right == nil {
+		return nil
+	}
 
 	return expr
 }
 
 func (p *Parser) parseWhileStmt() ast.Statement {
 	whileStmt := &ast.WhileStatement{Statements: []ast.Statement{}}
+	whileStmt.Start = p.curToken.Pos
 
 	if !p.expectNextToken(token.LPAREN) {
 		// may have to skip to end of line?
@@ -251,8 +540,8 @@ func (p *Parser) parseWhileStmt() ast.Statement {
 
 	p.nextToken()
 	p.nextToken()
-	whileStmt.Condition = p.parseExpression()
-	if !p.expectNextToken(token.RPAREN) {
+	whileStmt.Condition = p.parseExpression(LOWEST)
+	if whileStmt.Condition == nil || !p.expectNextToken(token.RPAREN) {
 		return nil
 	}
 
@@ -262,18 +551,16 @@ func (p *Parser) parseWhileStmt() ast.Statement {
 	}
 
 	p.nextToken()
-	p.nextToken()
-	// TODO: handle missing RBRACE
-	for p.curToken.Type != token.RBRACE {
-		whileStmt.Statements = append(whileStmt.Statements, p.parseStmt())
-		p.nextToken()
-	}
+	p.loopDepth++
+	whileStmt.Statements = p.parseBlock()
+	p.loopDepth--
 
 	return whileStmt
 }
 
 func (p *Parser) parseIfStmt() ast.Statement {
 	ifStmt := &ast.IfStatement{Statements: []ast.Statement{}}
+	ifStmt.Start = p.curToken.Pos
 
 	if !p.expectNextToken(token.LPAREN) {
 		// may have to skip to end of line?
@@ -282,8 +569,8 @@ func (p *Parser) parseIfStmt() ast.Statement {
 
 	p.nextToken()
 	p.nextToken()
-	ifStmt.Condition = p.parseExpression()
-	if !p.expectNextToken(token.RPAREN) {
+	ifStmt.Condition = p.parseExpression(LOWEST)
+	if ifStmt.Condition == nil || !p.expectNextToken(token.RPAREN) {
 		return nil
 	}
 
@@ -293,20 +580,73 @@ func (p *Parser) parseIfStmt() ast.Statement {
 	}
 
 	p.nextToken()
+	ifStmt.Statements = p.parseBlock()
+
+	if p.peekToken.Type != token.ELSE {
+		return ifStmt
+	}
+
 	p.nextToken()
-	for p.curToken.Type != token.RBRACE {
-		ifStmt.Statements = append(ifStmt.Statements, p.parseStmt())
+
+	// else if, parse the following if statement as the only statement in the else branch
+	if p.peekToken.Type == token.IF {
 		p.nextToken()
+		elseIf := p.parseIfStmt()
+		if elseIf == nil {
+			return nil
+		}
+
+		ifStmt.Alternative = []ast.Statement{elseIf}
+
+		return ifStmt
+	}
+
+	if !p.expectNextToken(token.LBRACE) {
+		return nil
 	}
 
+	p.nextToken()
+	ifStmt.Alternative = p.parseBlock()
+
 	return ifStmt
 }
 
+// parse the statements in a block, e.g. the body of a loop or function
+// expects curToken to be the opening brace and leaves curToken on the closing brace
+func (p *Parser) parseBlock() []ast.Statement {
+	stmts := []ast.Statement{}
+	p.nextToken()
+
+	for p.curToken.Type != token.RBRACE && p.curToken.Type != token.EOF {
+		// semicolons may be left over after function calls
+		if p.curToken.Type == token.SEMI {
+			p.nextToken()
+			continue
+		}
+
+		if stmt := p.parseStmt(); stmt != nil {
+			stmts = append(stmts, stmt)
+		} else {
+			p.synchronize()
+		}
+
+		p.nextToken()
+	}
+
+	if p.curToken.Type == token.EOF {
+		p.addError(p.curToken.Pos, "%s. Expected %s", unexpected(p.curToken), token.RBRACE)
+	}
+
+	return stmts
+}
+
 func (p *Parser) parseReturnStmt() ast.Statement {
+	start := p.curToken.Pos
 	p.nextToken()
-	returnStmt := &ast.ReturnStatement{ReturnVal: p.parseExpression()}
+	returnStmt := &ast.ReturnStatement{ReturnVal: p.parseExpression(LOWEST)}
+	returnStmt.Start = start
 
-	if !p.expectNextToken(token.SEMI) {
+	if returnStmt.ReturnVal == nil || !p.expectNextToken(token.SEMI) {
 		return nil
 	}
 
@@ -315,23 +655,126 @@ func (p *Parser) parseReturnStmt() ast.Statement {
 	return returnStmt
 }
 
-func (p *Parser) parseAssignStmt() ast.Statement {
-	assignStmt := &ast.AssignStatement{Identifier: p.curToken.Literal}
+func (p *Parser) parseThrowStmt() ast.Statement {
+	start := p.curToken.Pos
+	p.nextToken()
+	throwStmt := &ast.ThrowStatement{Value: p.parseExpression(LOWEST)}
+	throwStmt.Start = start
 
-	// TODO: don't use expectNextToken() here since it will cause unwanted errors
-	if !p.expectNextToken(token.ASSIGN) &&
-		!p.expectNextToken(token.PLUSEQ) &&
-		!p.expectNextToken(token.MINEQ) &&
-		!p.expectNextToken(token.MULTEQ) &&
-		!p.expectNextToken(token.DIVEQ) {
+	if throwStmt.Value == nil || !p.expectNextToken(token.SEMI) {
 		return nil
 	}
 
 	p.nextToken()
-	assignStmt.AssignOp = p.curToken.Literal
+
+	return throwStmt
+}
+
+func (p *Parser) parseTryStmt() ast.Statement {
+	tryStmt := &ast.TryStatement{}
+	tryStmt.Start = p.curToken.Pos
+
+	if !p.expectNextToken(token.LBRACE) {
+		return nil
+	}
+
+	p.nextToken()
+	tryStmt.Statements = p.parseBlock()
+
+	if !p.expectNextToken(token.CATCH) {
+		return nil
+	}
+
+	p.nextToken()
+
+	// the variable for the caught error is optional
+	if p.peekToken.Type == token.LPAREN {
+		p.nextToken()
+		if !p.expectNextToken(token.IDENT) {
+			return nil
+		}
+
+		p.nextToken()
+		tryStmt.CatchIdentifier = p.curToken.Literal
+
+		if !p.expectNextToken(token.RPAREN) {
+			return nil
+		}
+
+		p.nextToken()
+	}
+
+	if !p.expectNextToken(token.LBRACE) {
+		return nil
+	}
+
+	p.nextToken()
+	tryStmt.CatchStatements = p.parseBlock()
+
+	return tryStmt
+}
+
+func (p *Parser) parseImportStmt() ast.Statement {
+	start := p.curToken.Pos
+	if !p.expectNextToken(token.STRING) {
+		return nil
+	}
+
+	p.nextToken()
+	importStmt := &ast.ImportStatement{Path: p.curToken.Literal}
+	importStmt.Start = start
+
+	if !p.expectNextToken(token.AS) {
+		return nil
+	}
+
+	p.nextToken()
+	if !p.expectNextToken(token.IDENT) {
+		return nil
+	}
+
+	p.nextToken()
+	importStmt.Alias = p.curToken.Literal
+
+	if !p.expectNextToken(token.SEMI) {
+		return nil
+	}
+
+	p.nextToken()
+
+	return importStmt
+}
+
+// parse break or continue, these are only allowed inside of a loop
+func (p *Parser) parseLoopControlStmt() ast.Statement {
+	var stmt ast.Statement
+
+	if p.curToken.Type == token.BREAK {
+		stmt = &ast.BreakStatement{Pos: ast.Pos{Start: p.curToken.Pos}}
+	} else {
+		stmt = &ast.ContinueStatement{Pos: ast.Pos{Start: p.curToken.Pos}}
+	}
+
+	if p.loopDepth == 0 {
+		p.addError(p.curToken.Pos, "%s must be used inside of a loop", p.curToken.Literal)
+
+		return nil
+	}
+
+	if !p.expectNextToken(token.SEMI) {
+		return nil
+	}
+
 	p.nextToken()
 
-	assignStmt.Value = p.parseExpression()
+	return stmt
+}
+
+func (p *Parser) parseAssignStmt() ast.Statement {
+	assignStmt := p.parseAssignment()
+	if assignStmt == nil {
+		return nil
+	}
 
 	if !p.expectNextToken(token.SEMI) {
 		return nil
@@ -342,20 +785,63 @@ func (p *Parser) parseAssignStmt() ast.Statement {
 	return assignStmt
 }
 
-// TODO: handle function with no args
-func (p *Parser) parseFunctionDef() ast.Statement {
-	if !p.expectNextToken(token.IDENT) {
+// parse an assignment without the trailing semicolon, leaves curToken on the last token of the value
+// this is separate from parseAssignStmt so it can be used for the update in a for loop
+func (p *Parser) parseAssignment() *ast.AssignStatement {
+	target := p.parseExpression(LOWEST)
+	if target == nil {
+		return nil
+	}
+
+	return p.parseAssignmentTo(target)
+}
+
+// parse the assignment operator and value after the target has been parsed, e.g. the "+= 1" in xs[0] += 1
+func (p *Parser) parseAssignmentTo(target ast.Expression) *ast.AssignStatement {
+	if target == nil {
+		return nil
+	}
+
+	switch target := target.(type) {
+	case *ast.IdentifierExpression, *ast.ArrayIndexExpression:
+	case *ast.ObjectFunctionExpression:
+		// members can be assigned to, but the result of calling a function on an object can't
+		if _, ok := target.Function.(*ast.IdentifierExpression); !ok {
+			p.addError(target.Position(), "cannot assign to %s", target.ToString())
+
+			return nil
+		}
+	default:
+		p.addError(target.Position(), "cannot assign to %s", target.ToString())
+
+		return nil
+	}
+
+	assignStmt := &ast.AssignStatement{Target: target}
+	assignStmt.Start = target.Position()
+
+	switch p.peekToken.Type {
+	case token.ASSIGN, token.PLUSEQ, token.MINEQ, token.MULTEQ, token.DIVEQ:
+		p.nextToken()
+	default:
+		p.addError(p.peekToken.Pos, "%s. Expected an assignment operator", unexpected(p.peekToken))
+
 		return nil
 	}
 
+	assignStmt.AssignOp = p.curToken.Literal
 	p.nextToken()
 
-	funcDef := &ast.FunctionDef{
-		Name:       p.curToken.Literal,
-		Args:       []string{},
-		Statements: []ast.Statement{},
+	assignStmt.Value = p.parseExpression(LOWEST)
+	if assignStmt.Value == nil {
+		return nil
 	}
 
+	return assignStmt
+}
+
+func (p *Parser) parseForStmt() ast.Statement {
+	start := p.curToken.Pos
 	if !p.expectNextToken(token.LPAREN) {
 		return nil
 	}
@@ -363,62 +849,224 @@ func (p *Parser) parseFunctionDef() ast.Statement {
 	p.nextToken()
 	p.nextToken()
 
-	for p.curToken.Type == token.IDENT {
-		funcDef.Args = append(funcDef.Args, p.curToken.Literal)
+	if p.curToken.Type == token.IDENT && p.peekToken.Type == token.IN {
+		return p.parseForInStmt(start)
+	}
 
-		if p.peekToken.Type != token.RPAREN && p.peekToken.Type != token.COM {
-			errMsg := fmt.Sprintf("Unexpected token %s. Expected %s or %s", p.peekToken, token.RPAREN, token.COM)
-			p.Errors = append(p.Errors, errMsg)
+	forStmt := &ast.ForStatement{}
+	forStmt.Start = start
 
+	// the init statement is optional, var and assign statements both leave curToken on the semicolon
+	switch p.curToken.Type {
+	case token.SEMI:
+	case token.VAR:
+		forStmt.Init = p.parseVarStmt()
+		if forStmt.Init == nil {
+			return nil
+		}
+	case token.IDENT:
+		forStmt.Init = p.parseAssignStmt()
+		if forStmt.Init == nil {
+			return nil
+		}
+	default:
+		p.addError(p.curToken.Pos, "%s. Expected a var statement, assignment or %s", unexpected(p.curToken), token.SEMI)
+
+		return nil
+	}
+
+	// condition is optional, leaving it out loops forever
+	p.nextToken()
+	if p.curToken.Type != token.SEMI {
+		forStmt.Condition = p.parseExpression(LOWEST)
+		if forStmt.Condition == nil || !p.expectNextToken(token.SEMI) {
 			return nil
 		}
 
-		p.nextToken()
 		p.nextToken()
 	}
 
-	// in case there were no arguments, the loop was skipped
-	if p.curToken.Type == token.RPAREN {
+	// update is optional
+	p.nextToken()
+	if p.curToken.Type != token.RPAREN {
+		update := p.parseAssignment()
+		if update == nil {
+			return nil
+		}
+
+		forStmt.Update = update
+		if !p.expectNextToken(token.RPAREN) {
+			return nil
+		}
+
 		p.nextToken()
 	}
 
-	if p.curToken.Type != token.LBRACE {
-		errMsg := fmt.Sprintf("Unexpected token %s. Expected %s", p.curToken.Literal, token.LBRACE)
-		p.Errors = append(p.Errors, errMsg)
+	if !p.expectNextToken(token.LBRACE) {
+		return nil
+	}
+
+	p.nextToken()
+	p.loopDepth++
+	forStmt.Statements = p.parseBlock()
+	p.loopDepth--
+
+	return forStmt
+}
+
+// expects curToken to be the loop variable, start is the position of the for keyword
+func (p *Parser) parseForInStmt(start token.Position) ast.Statement {
+	forStmt := &ast.ForInStatement{Identifier: p.curToken.Literal}
+	forStmt.Start = start
+
+	p.nextToken()
+	p.nextToken()
+	forStmt.Collection = p.parseExpression(LOWEST)
+
+	if forStmt.Collection == nil || !p.expectNextToken(token.RPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	if !p.expectNextToken(token.LBRACE) {
+		return nil
+	}
+
+	p.nextToken()
+	p.loopDepth++
+	forStmt.Statements = p.parseBlock()
+	p.loopDepth--
 
+	return forStmt
+}
+
+func (p *Parser) parseFunctionDef() ast.Statement {
+	start := p.curToken.Pos
+	if !p.expectNextToken(token.IDENT) {
 		return nil
 	}
 
 	p.nextToken()
+	funcDef := &ast.FunctionDef{Name: p.curToken.Literal}
+	funcDef.Start = start
+
+	fn := p.parseFunctionArgsAndBody()
+	if fn == nil {
+		return nil
+	}
 
-	// TODO: handle missing rbrace
-	for p.curToken.Type != token.RBRACE {
-		funcDef.Statements = append(funcDef.Statements, p.parseStmt())
+	funcDef.Args = fn.Args
+	funcDef.Statements = fn.Statements
+
+	return funcDef
+}
+
+// anonymous function, e.g. var double = fun(x) { return x * 2; };
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	// avoid returning a nil *ast.FunctionLiteral as a non-nil ast.Expression
+	if fn := p.parseFunctionArgsAndBody(); fn != nil {
+		return fn
+	}
+
+	return nil
+}
+
+// parse the argument list and body shared by function definitions and function literals
+// expects peekToken to be the opening paren and leaves curToken on the closing brace of the body
+func (p *Parser) parseFunctionArgsAndBody() *ast.FunctionLiteral {
+	fn := &ast.FunctionLiteral{
+		Args:       []string{},
+		Statements: []ast.Statement{},
+	}
+	fn.Start = p.curToken.Pos
+
+	if !p.expectNextToken(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	for p.peekToken.Type == token.IDENT {
 		p.nextToken()
+		fn.Args = append(fn.Args, p.curToken.Literal)
 
-		if p.curToken.Type == token.SEMI {
+		if p.peekToken.Type != token.RPAREN && p.peekToken.Type != token.COM {
+			p.addError(p.peekToken.Pos, "%s. Expected %s or %s", unexpected(p.peekToken), token.RPAREN, token.COM)
+
+			return nil
+		}
+
+		if p.peekToken.Type == token.COM {
 			p.nextToken()
 		}
 	}
 
-	return funcDef
+	if !p.expectNextToken(token.RPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	p.nextToken()
+
+	if p.curToken.Type != token.LBRACE {
+		p.addError(p.curToken.Pos, "%s. Expected %s", unexpected(p.curToken), token.LBRACE)
+
+		return nil
+	}
+
+	// break and continue can't jump out of a function to a loop it's defined in
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	fn.Statements = p.parseBlock()
+	p.loopDepth = loopDepth
+
+	return fn
 }
 
 func (p *Parser) parseFunctionCall() ast.Statement {
-	funcCall := &ast.FunctionCall{Name: p.curToken.Literal, Args: []ast.Expression{}}
+	funcCall := &ast.FunctionCall{Name: p.curToken.Literal}
+	funcCall.Start = p.curToken.Pos
 	if !p.expectNextToken(token.LPAREN) {
 		return nil
 	}
 
 	p.nextToken()
+	funcCall.Args = p.parseCallArgs()
+	if funcCall.Args == nil {
+		return nil
+	}
+
+	return funcCall
+}
+
+// call on the result of an expression, e.g. makeCounter()() or fns[0](x)
+func (p *Parser) parseCallExpression(fn ast.Expression) ast.Expression {
+	call := &ast.CallExpression{Function: fn}
+	call.Start = startOf(fn, p.curToken)
+	call.Args = p.parseCallArgs()
+	if call.Args == nil {
+		return nil
+	}
+
+	return call
+}
+
+// parse the arguments passed to a function, expects curToken to be the opening paren
+// and leaves curToken on the closing paren. Returns nil if the arguments could not be parsed
+func (p *Parser) parseCallArgs() []ast.Expression {
+	args := []ast.Expression{}
 	p.nextToken()
 
 	for p.curToken.Type != token.RPAREN && p.curToken.Type != token.SEMI && p.curToken.Type != token.EOF {
-		funcCall.Args = append(funcCall.Args, p.parseExpression())
+		arg := p.parseExpression(LOWEST)
+		if arg == nil {
+			return nil
+		}
+
+		args = append(args, arg)
 
 		if p.peekToken.Type != token.RPAREN && p.peekToken.Type != token.COM {
-			errMsg := fmt.Sprintf("Unexpected token %s. Expected %s or %s", p.peekToken, token.RPAREN, token.COM)
-			p.Errors = append(p.Errors, errMsg)
+			p.addError(p.peekToken.Pos, "%s. Expected %s or %s", unexpected(p.peekToken), token.RPAREN, token.COM)
 
 			return nil
 		}
@@ -431,34 +1079,90 @@ func (p *Parser) parseFunctionCall() ast.Statement {
 	}
 
 	if p.curToken.Type == token.EOF {
-		errMsg := fmt.Sprintf("Unexpected token %s.", p.curToken.Literal)
-		p.Errors = append(p.Errors, errMsg)
+		p.addError(p.curToken.Pos, "%s.", unexpected(p.curToken))
 
 		return nil
 	}
 
-	return funcCall
+	return args
+}
+
+// statements that start with an identifier are either assignments, e.g. xs[0] += 1;
+// or function calls, the call may be followed by more calls, e.g. makeCounter()();
+func (p *Parser) parseIdentStmt() ast.Statement {
+	expr := p.parseExpression(LOWEST)
+	if expr == nil {
+		return nil
+	}
+
+	switch p.peekToken.Type {
+	case token.ASSIGN, token.PLUSEQ, token.MINEQ, token.MULTEQ, token.DIVEQ:
+		assignStmt := p.parseAssignmentTo(expr)
+		if assignStmt == nil {
+			return nil
+		}
+
+		if !p.expectNextToken(token.SEMI) {
+			return nil
+		}
+
+		p.nextToken()
+
+		return assignStmt
+	}
+
+	// calling a function on an object is a statement, but accessing a member is not
+	if objFn, ok := expr.(*ast.ObjectFunctionExpression); ok {
+		if _, isCall := objFn.Function.(*ast.FunctionCall); !isCall {
+			p.addError(expr.Position(), "Unexpected expression %s. Expected a function call or assignment", expr.ToString())
+
+			return nil
+		}
+	}
+
+	stmt, ok := expr.(ast.Statement)
+	if !ok {
+		p.addError(expr.Position(), "Unexpected expression %s. Expected a function call or assignment", expr.ToString())
+
+		return nil
+	}
+
+	return stmt
 }
 
 // this is just an infix expression where left is an object, op is '.' and right is the function call for that object
+// the right side can also be a member without a function call, e.g. config.port
 func (p *Parser) parseObjFuncExpression(obj ast.Expression) ast.Expression {
 	fnCall := &ast.ObjectFunctionExpression{Object: obj}
+	fnCall.Start = startOf(obj, p.curToken)
+	if !p.expectNextToken(token.IDENT) {
+		return nil
+	}
+
 	p.nextToken()
 	fnCall.Function = p.parseIdent()
+	if fnCall.Function == nil {
+		return nil
+	}
 
 	return fnCall
 }
 
 func (p *Parser) parseArray() ast.Expression {
-	p.nextToken()
 	arr := &ast.ArrayExpression{Items: []ast.Expression{}}
+	arr.Start = p.curToken.Pos
+	p.nextToken()
 
 	for p.curToken.Type != token.RBRACK && p.curToken.Type != token.SEMI && p.curToken.Type != token.EOF {
-		arr.Items = append(arr.Items, p.parseExpression())
+		item := p.parseExpression(LOWEST)
+		if item == nil {
+			return nil
+		}
+
+		arr.Items = append(arr.Items, item)
 
 		if p.peekToken.Type != token.RBRACK && p.peekToken.Type != token.COM {
-			errMsg := fmt.Sprintf("Unexpected token %s. Expected %s or %s", p.peekToken, token.RBRACK, token.COM)
-			p.Errors = append(p.Errors, errMsg)
+			p.addError(p.peekToken.Pos, "%s. Expected %s or %s", unexpected(p.peekToken), token.RBRACK, token.COM)
 
 			return nil
 		}
@@ -471,8 +1175,7 @@ func (p *Parser) parseArray() ast.Expression {
 	}
 
 	if p.curToken.Type == token.EOF {
-		errMsg := fmt.Sprintf("Unexpected token %s.", p.curToken.Literal)
-		p.Errors = append(p.Errors, errMsg)
+		p.addError(p.curToken.Pos, "%s.", unexpected(p.curToken))
 
 		return nil
 	}
@@ -480,15 +1183,56 @@ func (p *Parser) parseArray() ast.Expression {
 	return arr
 }
 
-func (p *Parser) parseIndexExpression(arr ast.Expression) ast.Expression {
-	idxExpr := &ast.ArrayIndexExpression{Arr: arr}
+func (p *Parser) parseMap() ast.Expression {
+	mapExpr := &ast.MapExpression{Keys: []ast.Expression{}, Values: []ast.Expression{}}
+	mapExpr.Start = p.curToken.Pos
 	p.nextToken()
-	idxExpr.Index = p.parseExpression()
 
-	if p.curToken.Type == token.RBRACK {
-		errMsg := fmt.Sprintf("Unexpected token %s.", p.curToken.Literal)
-		p.Errors = append(p.Errors, errMsg)
+	for p.curToken.Type != token.RBRACE && p.curToken.Type != token.EOF {
+		key := p.parseExpression(LOWEST)
+		if key == nil || !p.expectNextToken(token.COLON) {
+			return nil
+		}
 
+		p.nextToken()
+		p.nextToken()
+
+		val := p.parseExpression(LOWEST)
+		if val == nil {
+			return nil
+		}
+
+		mapExpr.Keys = append(mapExpr.Keys, key)
+		mapExpr.Values = append(mapExpr.Values, val)
+
+		if p.peekToken.Type != token.RBRACE && p.peekToken.Type != token.COM {
+			p.addError(p.peekToken.Pos, "%s. Expected %s or %s", unexpected(p.peekToken), token.RBRACE, token.COM)
+
+			return nil
+		}
+
+		p.nextToken()
+
+		if p.curToken.Type != token.RBRACE {
+			p.nextToken()
+		}
+	}
+
+	if p.curToken.Type == token.EOF {
+		p.addError(p.curToken.Pos, "%s.", unexpected(p.curToken))
+
+		return nil
+	}
+
+	return mapExpr
+}
+
+func (p *Parser) parseIndexExpression(arr ast.Expression) ast.Expression {
+	idxExpr := &ast.ArrayIndexExpression{Arr: arr}
+	idxExpr.Start = startOf(arr, p.curToken)
+	p.nextToken()
+	idxExpr.Index = p.parseExpression(LOWEST)
+	if idxExpr.Index == nil || !p.expectNextToken(token.RBRACK) {
 		return nil
 	}
 
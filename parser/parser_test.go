@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/MarkyMan4/yetti/ast"
@@ -166,3 +167,645 @@ func TestParseRecursiveFunction(t *testing.T) {
 		// fmt.Println(funcDef.Statements[i].ToString())
 	}
 }
+
+func TestParseIfElse(t *testing.T) {
+	l := lexer.NewLexer("var x = 1; if(x < 0) {x = 0;} else if(x < 5) {print(x); x += 1;} else {x = 5;}")
+	p := NewParser(l)
+	prog := p.Parse()
+
+	if len(prog.Statements) != 2 {
+		t.Fatalf("expected %d statements but found %d\n", 2, len(prog.Statements))
+	}
+
+	ifStmt, ok := prog.Statements[1].(*ast.IfStatement)
+	if !ok {
+		t.Fatal("failed to parse if statement")
+	}
+
+	if len(ifStmt.Alternative) != 1 {
+		t.Fatalf("expected else branch with 1 statement but found %d\n", len(ifStmt.Alternative))
+	}
+
+	elseIf, ok := ifStmt.Alternative[0].(*ast.IfStatement)
+	if !ok {
+		t.Fatal("failed to parse else if statement")
+	}
+
+	if len(elseIf.Statements) != 2 {
+		t.Fatalf("expected %d statements in else if but found %d\n", 2, len(elseIf.Statements))
+	}
+
+	if len(elseIf.Alternative) != 1 {
+		t.Fatal("failed to parse else statement")
+	}
+}
+
+func TestParseFor(t *testing.T) {
+	l := lexer.NewLexer("var total = 0; for(var i = 0; i < 10; i += 1) {total += i;} for(;;) {print(total);}")
+	p := NewParser(l)
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("unexpected parse errors: %v\n", p.Errors)
+	}
+
+	forStmt, ok := prog.Statements[1].(*ast.ForStatement)
+	if !ok {
+		t.Fatal("failed to parse for statement")
+	}
+
+	if forStmt.Init == nil || forStmt.Condition == nil || forStmt.Update == nil {
+		t.Fatal("failed to parse for loop header")
+	}
+
+	if len(forStmt.Statements) != 1 {
+		t.Fatalf("expected %d statements in for loop but found %d\n", 1, len(forStmt.Statements))
+	}
+
+	emptyFor, ok := prog.Statements[2].(*ast.ForStatement)
+	if !ok {
+		t.Fatal("failed to parse for statement with empty header")
+	}
+
+	if emptyFor.Init != nil || emptyFor.Condition != nil || emptyFor.Update != nil {
+		t.Fatal("expected empty for loop header")
+	}
+}
+
+func TestParseForIn(t *testing.T) {
+	l := lexer.NewLexer("for(x in [1, 2, 3]) {print(x);}")
+	p := NewParser(l)
+	prog := p.Parse()
+
+	forIn, ok := prog.Statements[0].(*ast.ForInStatement)
+	if !ok {
+		t.Fatal("failed to parse for in statement")
+	}
+
+	if forIn.Identifier != "x" {
+		t.Fatalf("expected loop variable x but got %s\n", forIn.Identifier)
+	}
+}
+
+func TestParseBreakContinue(t *testing.T) {
+	l := lexer.NewLexer("while(true) { if(x) { break; } continue; }")
+	p := NewParser(l)
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("unexpected parse errors: %v\n", p.Errors)
+	}
+
+	whileStmt := prog.Statements[0].(*ast.WhileStatement)
+	if _, ok := whileStmt.Statements[1].(*ast.ContinueStatement); !ok {
+		t.Fatal("failed to parse continue statement")
+	}
+
+	// break is not allowed outside of a loop, including in a function defined inside a loop
+	for _, input := range []string{"break;", "while(true) { fun f() { continue; } }"} {
+		p = NewParser(lexer.NewLexer(input))
+		p.Parse()
+
+		if len(p.Errors) == 0 {
+			t.Fatalf("expected an error parsing %s\n", input)
+		}
+	}
+}
+
+func TestParseLogicalOperators(t *testing.T) {
+	l := lexer.NewLexer("var ok = i < xs.length() && xs[i] > 0 || !done;")
+	p := NewParser(l)
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("unexpected parse errors: %v\n", p.Errors)
+	}
+
+	// || binds looser than &&, which binds looser than comparisons
+	or, ok := prog.Statements[0].(*ast.VarStatement).Value.(*ast.InfixExpression)
+	if !ok || or.Op != "||" {
+		t.Fatal("expected || to be the outermost expression")
+	}
+
+	and, ok := or.Left.(*ast.InfixExpression)
+	if !ok || and.Op != "&&" {
+		t.Fatal("expected && on the left side of ||")
+	}
+
+	if _, ok := or.Right.(*ast.PrefixExpression); !ok {
+		t.Fatal("expected prefix expression on the right side of ||")
+	}
+
+	if lt, ok := and.Left.(*ast.InfixExpression); !ok || lt.Op != "<" {
+		t.Fatal("expected < on the left side of &&")
+	}
+
+	if gt, ok := and.Right.(*ast.InfixExpression); !ok || gt.Op != ">" {
+		t.Fatal("expected > on the right side of &&")
+	}
+}
+
+func TestParseOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 * 3 + 4", "((2 * 3) + 4)"},
+		{"2 + 3 * 4", "(2 + (3 * 4))"},
+		{"10 - 2 - 3", "((10 - 2) - 3)"},
+		{"8 / 4 / 2", "((8 / 4) / 2)"},
+		{"(2 + 3) * 4", "((2 + 3) * 4)"},
+		{"2 * (3 + 4) - 1", "((2 * (3 + 4)) - 1)"},
+		{"1 + 2 < 4 == true", "(((1 + 2) < 4) == true)"},
+		{"a < b && c >= d || e", "(((a < b) && (c >= d)) || e)"},
+		{"!a == b", "((!a) == b)"},
+		{"xs[1] + xs[2] * 2", "(xs[1] + (xs[2] * 2))"},
+		{"s.length() - 1", "(s.length() - 1)"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer("var x = " + tt.input + ";"))
+		prog := p.Parse()
+
+		if len(p.Errors) > 0 {
+			t.Fatalf("unexpected parse errors for %s: %v\n", tt.input, p.Errors)
+		}
+
+		actual := prog.Statements[0].(*ast.VarStatement).Value.ToString()
+		if actual != tt.expected {
+			t.Fatalf("expected %s to parse as %s but got %s\n", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestParsePrefixExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"-5", "(-5)"},
+		{"+x", "(+x)"},
+		{"2 - -3", "(2 - (-3))"},
+		{"-2 * 3", "((-2) * 3)"},
+		{"-xs[0]", "(-xs[0])"},
+		{"-s.length()", "(-s.length())"},
+		{"!(a && b)", "(!(a && b))"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer("return " + tt.input + ";"))
+		prog := p.Parse()
+
+		if len(p.Errors) > 0 {
+			t.Fatalf("unexpected parse errors for %s: %v\n", tt.input, p.Errors)
+		}
+
+		actual := prog.Statements[0].(*ast.ReturnStatement).ReturnVal.ToString()
+		if actual != tt.expected {
+			t.Fatalf("expected %s to parse as %s but got %s\n", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestParseNull(t *testing.T) {
+	p := NewParser(lexer.NewLexer("var x = null; var y = x == null;"))
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("unexpected parse errors: %v\n", p.Errors)
+	}
+
+	if _, ok := prog.Statements[0].(*ast.VarStatement).Value.(*ast.NullLiteral); !ok {
+		t.Fatalf("expected a null literal but got %s\n", prog.Statements[0].ToString())
+	}
+
+	actual := prog.Statements[1].(*ast.VarStatement).Value.ToString()
+	if actual != "(x == null)" {
+		t.Fatalf("expected x == null to parse as (x == null) but got %s\n", actual)
+	}
+
+	// null is a keyword so it can't be used as a variable name
+	p = NewParser(lexer.NewLexer("var null = 1;"))
+	p.Parse()
+
+	if len(p.Errors) == 0 {
+		t.Fatal("expected an error declaring a variable named null")
+	}
+}
+
+func TestParseFunctionLiteral(t *testing.T) {
+	l := lexer.NewLexer("var double = fun(x) { return x * 2; }; makeCounter()(); var y = fns[0](1, 2);")
+	p := NewParser(l)
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("unexpected parse errors: %v\n", p.Errors)
+	}
+
+	if len(prog.Statements) != 3 {
+		t.Fatalf("expected %d statements but found %d\n", 3, len(prog.Statements))
+	}
+
+	fn, ok := prog.Statements[0].(*ast.VarStatement).Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatal("failed to parse function literal")
+	}
+
+	if len(fn.Args) != 1 || len(fn.Statements) != 1 {
+		t.Fatal("failed to parse function literal arguments and body")
+	}
+
+	call, ok := prog.Statements[1].(*ast.CallExpression)
+	if !ok {
+		t.Fatal("failed to parse call on the result of a function call")
+	}
+
+	if _, ok := call.Function.(*ast.FunctionCall); !ok {
+		t.Fatal("expected the inner call to be a function call")
+	}
+
+	idxCall, ok := prog.Statements[2].(*ast.VarStatement).Value.(*ast.CallExpression)
+	if !ok || len(idxCall.Args) != 2 {
+		t.Fatal("failed to parse call on an array index")
+	}
+}
+
+func TestParseMap(t *testing.T) {
+	l := lexer.NewLexer(`var m = {"a": 1, 2: [1, 2], true: {"nested": 1 + 2}}; var x = m["a"]; var e = {};`)
+	p := NewParser(l)
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("unexpected parse errors: %v\n", p.Errors)
+	}
+
+	mapExpr, ok := prog.Statements[0].(*ast.VarStatement).Value.(*ast.MapExpression)
+	if !ok {
+		t.Fatal("failed to parse map literal")
+	}
+
+	if len(mapExpr.Keys) != 3 || len(mapExpr.Values) != 3 {
+		t.Fatalf("expected 3 key value pairs but found %d\n", len(mapExpr.Keys))
+	}
+
+	if _, ok := prog.Statements[1].(*ast.VarStatement).Value.(*ast.ArrayIndexExpression); !ok {
+		t.Fatal("failed to parse map index")
+	}
+
+	if empty, ok := prog.Statements[2].(*ast.VarStatement).Value.(*ast.MapExpression); !ok || len(empty.Keys) != 0 {
+		t.Fatal("failed to parse empty map")
+	}
+}
+
+func TestParseNestedIndex(t *testing.T) {
+	p := NewParser(lexer.NewLexer("var x = xs[ys[1]]; var y = grid[rows[i]][cols[j]]; counts[words[i]] += 1; xs[ys[0]] = m[k[0]];"))
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("unexpected parse errors: %v\n", p.Errors)
+	}
+
+	expected := []string{
+		"var x = xs[ys[1]];",
+		"var y = grid[rows[i]][cols[j]];",
+		"counts[words[i]] += 1;",
+		"xs[ys[0]] = m[k[0]];",
+	}
+
+	for i := range expected {
+		if prog.Statements[i].ToString() != expected[i] {
+			t.Fatalf("expected %s but got %s\n", expected[i], prog.Statements[i].ToString())
+		}
+	}
+
+	// an index must be followed by a closing bracket
+	p = NewParser(lexer.NewLexer("print(xs[1 2]);"))
+	p.Parse()
+
+	if len(p.Errors) == 0 || p.Errors[0].Message != "Expected next token to be ], but got 2" {
+		t.Fatalf("expected a missing ] error but got %v\n", p.Errors)
+	}
+}
+
+func TestParseAssignTargets(t *testing.T) {
+	l := lexer.NewLexer(`xs[2] = 10; grid[i][j] += 1; m["k"] -= 2; config.port *= 2; x /= 3; xs.append(1);`)
+	p := NewParser(l)
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("unexpected parse errors: %v\n", p.Errors)
+	}
+
+	expected := []string{"xs[2] = 10;", "grid[i][j] += 1;", "m[k] -= 2;", "config.port *= 2;", "x /= 3;"}
+
+	for i := range expected {
+		assignStmt, ok := prog.Statements[i].(*ast.AssignStatement)
+		if !ok {
+			t.Fatalf("failed to parse assignment %d\n", i)
+		}
+
+		if assignStmt.ToString() != expected[i] {
+			t.Fatalf("expected %s but got %s\n", expected[i], assignStmt.ToString())
+		}
+	}
+
+	if _, ok := prog.Statements[5].(*ast.ObjectFunctionExpression); !ok {
+		t.Fatal("failed to parse function call on object as a statement")
+	}
+
+	// function calls and other expressions can't be assigned to
+	for _, input := range []string{"f() = 1;", "xs.length() = 2;", "config.port;"} {
+		p = NewParser(lexer.NewLexer(input))
+		p.Parse()
+
+		if len(p.Errors) == 0 {
+			t.Fatalf("expected an error parsing %s\n", input)
+		}
+	}
+}
+
+func TestParseTryCatch(t *testing.T) {
+	l := lexer.NewLexer(`try { throw "bad"; } catch(e) { print(e); } try { f(); } catch { x = 1; }`)
+	p := NewParser(l)
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("unexpected parse errors: %v\n", p.Errors)
+	}
+
+	tryStmt, ok := prog.Statements[0].(*ast.TryStatement)
+	if !ok {
+		t.Fatal("failed to parse try statement")
+	}
+
+	if _, ok := tryStmt.Statements[0].(*ast.ThrowStatement); !ok {
+		t.Fatal("failed to parse throw statement")
+	}
+
+	if tryStmt.CatchIdentifier != "e" || len(tryStmt.CatchStatements) != 1 {
+		t.Fatal("failed to parse catch block")
+	}
+
+	noIdent, ok := prog.Statements[1].(*ast.TryStatement)
+	if !ok || noIdent.CatchIdentifier != "" {
+		t.Fatal("failed to parse catch block without an identifier")
+	}
+}
+
+func TestParseImport(t *testing.T) {
+	l := lexer.NewLexer(`import "lib/strings.yti" as s;`)
+	p := NewParser(l)
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("unexpected parse errors: %v\n", p.Errors)
+	}
+
+	importStmt, ok := prog.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatal("failed to parse import statement")
+	}
+
+	if importStmt.Path != "lib/strings.yti" || importStmt.Alias != "s" {
+		t.Fatalf("expected import of lib/strings.yti as s but got %s as %s\n", importStmt.Path, importStmt.Alias)
+	}
+
+	for _, input := range []string{`import "a.yti";`, `import a as b;`, `import "a.yti" as "b";`} {
+		p = NewParser(lexer.NewLexer(input))
+		p.Parse()
+
+		if len(p.Errors) == 0 {
+			t.Fatalf("expected an error parsing %s\n", input)
+		}
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	l := lexer.NewLexer("var x = 1;\nif(x > 0) {\n    xs[0] += f(x);\n}")
+	p := NewParser(l)
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("unexpected parse errors: %v\n", p.Errors)
+	}
+
+	ifStmt := prog.Statements[1].(*ast.IfStatement)
+	assignStmt := ifStmt.Statements[0].(*ast.AssignStatement)
+
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{prog.Statements[0], "1:1"},
+		{prog.Statements[0].(*ast.VarStatement).Value, "1:9"},
+		{ifStmt, "2:1"},
+		{ifStmt.Condition, "2:4"},
+		{ifStmt.Condition.(*ast.InfixExpression).Right, "2:8"},
+		{assignStmt, "3:5"},
+		{assignStmt.Value, "3:14"},
+	}
+
+	for _, tt := range tests {
+		if tt.node.Position().String() != tt.expected {
+			t.Fatalf("expected %s to start at %s but got %s\n", tt.node.ToString(), tt.expected, tt.node.Position())
+		}
+	}
+}
+
+func TestParseExpressionInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // parsed expression, or the first error if it isn't an expression
+	}{
+		{"x + 1", "(x + 1)"},
+		{"f(1);", "f(1)"},
+		{"1 +", "Unexpected end of input. Expected an expression"},
+		{"[1, 2 3]", "Unexpected token 3. Expected ] or ,"},
+		{"x = 1;", ""},
+		{"1 + ;", ""},
+		{"var x = 1", ""},
+		{"fun f() { return 1; }", ""},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		expr := p.ParseExpressionInput()
+
+		actual := ""
+		if expr != nil {
+			actual = expr.ToString()
+		} else if len(p.Errors) > 0 {
+			actual = p.Errors[0].Message
+		}
+
+		if actual != tt.expected {
+			t.Errorf("expected %q for %s but got %q\n", tt.expected, tt.input, actual)
+		}
+	}
+}
+
+func TestParseErrorPositions(t *testing.T) {
+	p := NewParser(lexer.NewLexer("var x = 1;\nvar = 2;"))
+	p.Parse()
+
+	if len(p.Errors) == 0 {
+		t.Fatal("expected a parse error")
+	}
+
+	if p.Errors[0].Error() != "2:5: Expected next token to be IDENT, but got =" {
+		t.Fatalf("unexpected parse error: %s\n", p.Errors[0].Error())
+	}
+}
+
+func TestParseErrorRecovery(t *testing.T) {
+	input := `
+		var x = 1;
+		var = 2;
+		fun f(a b) {
+			return a;
+		}
+		while(x < 10) {
+			x += ;
+			print(x);
+		}
+		if() {
+			x = 2;
+		}
+		var y = [1, 2;
+		print("done");
+	`
+	p := NewParser(lexer.NewLexer(input))
+	prog := p.Parse()
+
+	expected := []string{
+		"3:7: Expected next token to be IDENT, but got =",
+		"4:11: Unexpected token b. Expected ) or ,",
+		"8:9: Unexpected token ;. Expected an expression",
+		"11:6: Unexpected token ). Expected an expression",
+		"14:16: Unexpected token ;. Expected ] or ,",
+	}
+
+	if len(p.Errors) != len(expected) {
+		t.Fatalf("expected %d errors but got %d: %v\n", len(expected), len(p.Errors), p.Errors)
+	}
+
+	for i := range expected {
+		if p.Errors[i].Error() != expected[i] {
+			t.Fatalf("expected error %s but got %s\n", expected[i], p.Errors[i].Error())
+		}
+	}
+
+	// statements that could be parsed are kept, and there are no nil statements in the program
+	for _, stmt := range prog.Statements {
+		if stmt == nil {
+			t.Fatal("program contains a nil statement")
+		}
+	}
+
+	if len(prog.Statements) != 3 {
+		t.Fatalf("expected 3 statements but got %d\n", len(prog.Statements))
+	}
+
+	whileStmt, ok := prog.Statements[1].(*ast.WhileStatement)
+	if !ok || len(whileStmt.Statements) != 1 {
+		t.Fatal("failed to recover inside of while loop")
+	}
+
+	// expressions that can't be parsed inside of other expressions are reported, not added to the tree
+	for _, input := range []string{"f(,) = 3;", "x[f(,)];", "x + f(,);", "x.f(,).g;", "x[{1:,}];", "x[0] + [,];", "var y = -(f(,));"} {
+		p := NewParser(lexer.NewLexer(input))
+		p.Parse()
+
+		if len(p.Errors) == 0 || !strings.HasPrefix(p.Errors[0].Message, "Unexpected token ,") {
+			t.Errorf("expected an error for the unexpected comma in %s, got %v\n", input, p.Errors)
+		}
+	}
+
+	// the parameter list of a function has to be closed before its body
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fun f( { } print(1);", "1:8: Expected next token to be ), but got {"},
+		{"fun f(a, { }", "1:10: Expected next token to be ), but got {"},
+		{"var g = fun(a { };", "1:15: Unexpected token {. Expected ) or ,"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		p.Parse()
+
+		if len(p.Errors) == 0 || p.Errors[0].Error() != tt.expected {
+			t.Errorf("expected error %s for %s but got %v\n", tt.expected, tt.input, p.Errors)
+		}
+	}
+}
+
+func TestParseErrorsAtEndOfInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var x = 1", "1:10: Expected next token to be ;, but got end of input"},
+		{"print(1", "1:8: Unexpected end of input. Expected ) or ,"},
+		{"var xs = [1", "1:12: Unexpected end of input. Expected ] or ,"},
+		{"while(true) {", "1:14: Unexpected end of input. Expected }"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		p.Parse()
+
+		if len(p.Errors) == 0 || p.Errors[0].Error() != tt.expected {
+			t.Errorf("expected error %q for %q, got %v\n", tt.expected, tt.input, p.Errors)
+		}
+	}
+}
+
+func TestParseIllegalTokens(t *testing.T) {
+	input := "var x = 1 @ 2;\nvar y = a & b;\nvar s = \"abc;"
+	p := NewParser(lexer.NewLexer(input))
+	p.Parse()
+
+	expected := []string{
+		"1:11: illegal character @",
+		"2:11: illegal character &",
+		"3:9: unterminated string",
+	}
+
+	if len(p.Errors) != len(expected) {
+		t.Fatalf("expected %d errors but got %d: %v\n", len(expected), len(p.Errors), p.Errors)
+	}
+
+	for i := range expected {
+		if p.Errors[i].Error() != expected[i] {
+			t.Fatalf("expected error %s but got %s\n", expected[i], p.Errors[i].Error())
+		}
+	}
+}
+
+func TestParseInterpolatedString(t *testing.T) {
+	p := NewParser(lexer.NewLexer(`var s = "sum: ${a + b}";`))
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("unexpected parse errors: %v\n", p.Errors)
+	}
+
+	str, ok := prog.Statements[0].(*ast.VarStatement).Value.(*ast.InterpolatedString)
+	if !ok || len(str.Parts) != 2 {
+		t.Fatal("failed to parse interpolated string")
+	}
+
+	// positions of embedded expressions are relative to the start of the input
+	if str.Parts[1].ToString() != "(a + b)" || str.Parts[1].Position().String() != "1:17" {
+		t.Fatalf("unexpected embedded expression %s at %s\n", str.Parts[1].ToString(), str.Parts[1].Position())
+	}
+
+	p = NewParser(lexer.NewLexer("var x = 1;\nvar s = \"bad: ${x +}\";"))
+	p.Parse()
+
+	if len(p.Errors) != 1 || p.Errors[0].Error() != "2:20: Unexpected end of input. Expected an expression" {
+		t.Fatalf("unexpected parse errors: %v\n", p.Errors)
+	}
+}
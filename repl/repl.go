@@ -0,0 +1,176 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/MarkyMan4/yetti/ast"
+	"github.com/MarkyMan4/yetti/diagnostics"
+	"github.com/MarkyMan4/yetti/evaluator"
+	"github.com/MarkyMan4/yetti/lexer"
+	"github.com/MarkyMan4/yetti/object"
+	"github.com/MarkyMan4/yetti/parser"
+	"github.com/MarkyMan4/yetti/token"
+)
+
+const (
+	PROMPT          = ">> "
+	CONTINUE_PROMPT = ".. "
+)
+
+// read lines from in and evaluate them one at a time, writing results and errors to out
+// all inputs share one environment, so variables and functions carry over between lines
+// programs also print to out, and builtins like input read from in
+// the session ends at the end of input or when exit() is called, the exit status is returned
+func Start(in io.Reader, out io.Writer) int {
+	// lines are read through the runtime so input read by the program isn't buffered away from it
+	rt := object.NewRuntime(in, out, out)
+	env := object.NewEnvironment()
+	env.SetRuntime(rt)
+	defer rt.CloseFiles()
+
+	// functions keep the file they were defined in, so each input is named and its source kept to
+	// show errors raised by a function from an earlier input against the line it came from
+	sources := map[string]string{}
+
+	for {
+		fmt.Fprint(out, PROMPT)
+
+		src, ok := readInput(rt, out)
+		if !ok {
+			fmt.Fprintln(out)
+			return 0
+		}
+
+		if strings.TrimSpace(src) == "" {
+			continue
+		}
+
+		name := fmt.Sprintf("<input %d>", len(sources)+1)
+		sources[name] = src
+		env.SetFile(name)
+
+		if exitObj := evalInput(name, src, env, sources, out); exitObj != nil {
+			return exitObj.Code
+		}
+	}
+}
+
+// read a line of input, continuing onto the next lines while there are unclosed braces, brackets or parens
+func readInput(rt *object.Runtime, out io.Writer) (string, bool) {
+	src, ok, _ := rt.ReadLine()
+	if !ok {
+		return "", false
+	}
+
+	for isIncomplete(src) {
+		fmt.Fprint(out, CONTINUE_PROMPT)
+
+		line, ok, _ := rt.ReadLine()
+		if !ok {
+			break
+		}
+
+		src += "\n" + line
+	}
+
+	return src, true
+}
+
+// check whether the input has more opening braces, brackets or parens than closing ones,
+// or ends in the middle of a string that can span multiple lines
+func isIncomplete(src string) bool {
+	l := lexer.NewLexer(src)
+	depth := 0
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.ILLEGAL:
+			if strings.HasPrefix(tok.Literal, "\"\"\"") || strings.HasPrefix(tok.Literal, "`") {
+				return true
+			}
+		case token.LBRACE, token.LBRACK, token.LPAREN:
+			depth++
+		case token.RBRACE, token.RBRACK, token.RPAREN:
+			depth--
+		}
+	}
+
+	return depth > 0
+}
+
+// evaluate one input, echoing the value if the input is an expression
+// if the input calls exit(), the error ending the program is returned
+func evalInput(name string, src string, env *object.Environment, sources map[string]string, out io.Writer) *object.ErrorObject {
+	p := parser.NewParser(lexer.NewLexer(src))
+
+	if expr := p.ParseExpressionInput(); expr != nil {
+		res := evaluator.Eval(expr, env)
+
+		if errObj, ok := res.(*object.ErrorObject); ok {
+			if errObj.Exit {
+				return errObj
+			}
+
+			fmt.Fprintln(out, formatError(errObj, name, sources))
+			return nil
+		}
+
+		// calls to functions like print return null, which isn't worth echoing
+		if _, isNull := res.(*object.NullObject); res != nil && !isNull {
+			fmt.Fprintln(out, res.ToString())
+		}
+
+		return nil
+	}
+
+	// errors are only kept if the input looked like an expression but couldn't be parsed as one,
+	// anything else is parsed again as a program
+	var prog *ast.Program
+
+	if len(p.Errors) == 0 {
+		p = parser.NewParser(lexer.NewLexer(src))
+		prog = p.Parse()
+	}
+
+	if len(p.Errors) > 0 {
+		for _, err := range p.Errors {
+			fmt.Fprintln(out, diagnostics.Format("", src, err.Pos, err.Message))
+		}
+
+		return nil
+	}
+
+	for i := range prog.Statements {
+		if errObj, ok := evaluator.Eval(prog.Statements[i], env).(*object.ErrorObject); ok {
+			if errObj.Exit {
+				return errObj
+			}
+
+			fmt.Fprintln(out, formatError(errObj, name, sources))
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// format a runtime error with the line it happened on. Errors from the current input are shown
+// without a name, errors raised in a function from an earlier input are shown with that input's
+// name and line, and errors raised in an imported module show the line from that module's file
+func formatError(errObj *object.ErrorObject, name string, sources map[string]string) string {
+	if errObj.File == name {
+		return diagnostics.Format("", sources[name], errObj.Pos, errObj.ToString())
+	}
+
+	src, ok := sources[errObj.File]
+	if !ok {
+		if contents, err := os.ReadFile(errObj.File); err == nil {
+			src = string(contents)
+		}
+	}
+
+	return diagnostics.Format(errObj.File, src, errObj.Pos, errObj.ToString())
+}
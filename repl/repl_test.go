@@ -0,0 +1,120 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRepl(t *testing.T) {
+	input := strings.Join([]string{
+		`var x = 2;`,
+		`x * 3`,
+		`fun add(a, b) {`,
+		`    return a + b;`,
+		`}`,
+		`add(x, 5);`,
+		`var = 1;`,
+		`var y = z;`,
+		`x += 1;`,
+		`x`,
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := []string{
+		">> >> 6",
+		">> .. .. >> 7",
+		">> 1:5: Expected next token to be IDENT, but got =",
+		"var = 1;",
+		"    ^",
+		">> 1:9: NameError: identifier z is not defined",
+		"var y = z;",
+		"        ^",
+		">> >> 3",
+		">> ",
+		"",
+	}
+
+	if out.String() != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected output from repl:\n%s\n", out.String())
+	}
+}
+
+func TestReplExpressionErrors(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader("1 +\n1 + ;"), &out)
+
+	// input without a semicolon that starts like an expression gets the errors from parsing it as one
+	expected := []string{
+		">> 1:4: Unexpected end of input. Expected an expression",
+		"1 +",
+		"   ^",
+		">> 1:1: Unexpected token 1. Expected a statement",
+		"1 + ;",
+		"^",
+		">> ",
+		"",
+	}
+
+	if out.String() != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected output from repl:\n%s\n", out.String())
+	}
+}
+
+func TestReplErrorInEarlierInput(t *testing.T) {
+	input := strings.Join([]string{
+		`fun f() { return 1 + true; }`,
+		`var x = 1;`,
+		`f()`,
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	// the error is shown against the input the function was defined in, not the call
+	expected := []string{
+		">> >> >> <input 1>:1:18: TypeError: unsupported operator '+' for types INTEGER, BOOLEAN",
+		"fun f() { return 1 + true; }",
+		"                 ^",
+		">> ",
+		"",
+	}
+
+	if out.String() != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected output from repl:\n%s\n", out.String())
+	}
+}
+
+func TestIsIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"var x = 1;", false},
+		{"fun f() {", true},
+		{"var xs = [1, 2,", true},
+		{"print(\"{\");", false},
+		{"if(x) { while(true) { }", true},
+		{"}", false},
+		{"var s = \"\"\"first line", true},
+		{"var s = `C:\\", true},
+		{"var s = \"unterminated", false},
+	}
+
+	for _, tt := range tests {
+		if isIncomplete(tt.input) != tt.expected {
+			t.Fatalf("expected isIncomplete(%q) to be %v\n", tt.input, tt.expected)
+		}
+	}
+}
+
+func TestReplExit(t *testing.T) {
+	var out bytes.Buffer
+	code := Start(strings.NewReader("var x = 2;\nexit(x + 1)\nprint(x);"), &out)
+
+	if code != 3 || out.String() != ">> >> " {
+		t.Fatalf("expected the session to end with status 3, got %d and output %q\n", code, out.String())
+	}
+}
@@ -0,0 +1,414 @@
+package stdlib
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+
+	"github.com/MarkyMan4/yetti/object"
+)
+
+/*
+--------------------------------------
+filesystem operations
+--------------------------------------
+*/
+
+// wrap an error from the os package, errors for missing files, existing files and permissions get
+// their own kind so scripts can catch them separately
+func fsError(err error, format string, a ...interface{}) *object.ErrorObject {
+	kind := object.IO_ERROR
+
+	switch {
+	case errors.Is(err, syscall.ENOTEMPTY):
+		// Go reports a directory that isn't empty as already existing, but it should be an IOError
+	case errors.Is(err, fs.ErrNotExist):
+		kind = object.FILE_NOT_FOUND_ERROR
+	case errors.Is(err, fs.ErrExist):
+		kind = object.FILE_EXISTS_ERROR
+	case errors.Is(err, fs.ErrPermission):
+		kind = object.PERMISSION_ERROR
+	}
+
+	errObj := object.NewError(kind, format, a...)
+	errObj.Message += " - " + err.Error()
+
+	return errObj
+}
+
+// check a function was called with the right number of string arguments and get their values,
+// maxArgs is -1 for functions that take any number of arguments
+func stringArgs(fnName string, args []object.Object, minArgs int, maxArgs int) ([]string, *object.ErrorObject) {
+	if len(args) < minArgs || (maxArgs >= 0 && len(args) > maxArgs) {
+		if maxArgs < 0 {
+			return nil, object.NewError(object.ARGUMENT_ERROR, "%s expects at least %d arguments but received %d", fnName, minArgs, len(args))
+		} else if minArgs == maxArgs {
+			return nil, object.NewError(object.ARGUMENT_ERROR, "%s expects %d arguments but received %d", fnName, minArgs, len(args))
+		}
+
+		return nil, object.NewError(object.ARGUMENT_ERROR, "%s expects %d to %d arguments but received %d", fnName, minArgs, maxArgs, len(args))
+	}
+
+	vals := make([]string, len(args))
+
+	for i := range args {
+		str, ok := args[i].(*object.StringObject)
+		if !ok {
+			return nil, object.NewError(object.TYPE_ERROR, "arguments to %s must be strings, got %s", fnName, args[i].Type())
+		}
+
+		vals[i] = str.Value
+	}
+
+	return vals, nil
+}
+
+func stringArray(vals []string) *object.ArrayObject {
+	arr := &object.ArrayObject{Items: []object.Object{}}
+
+	for _, val := range vals {
+		arr.Items = append(arr.Items, &object.StringObject{Value: val})
+	}
+
+	return arr
+}
+
+// get the names of the entries in a directory, sorted by name
+func ListDirFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("listDir", args, 1, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	entries, err := os.ReadDir(vals[0])
+	if err != nil {
+		return fsError(err, "failed to list directory %s", vals[0])
+	}
+
+	names := make([]string, len(entries))
+	for i := range entries {
+		names[i] = entries[i].Name()
+	}
+
+	return stringArray(names)
+}
+
+// get the paths of every file and directory under a directory, parents come before their children
+func WalkFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("walk", args, 1, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	root := vals[0]
+	paths := []string{}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path != root {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fsError(err, "failed to walk directory %s", root)
+	}
+
+	return stringArray(paths)
+}
+
+// get the paths matching a pattern such as "logs/*.txt", sorted by name
+func GlobFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("glob", args, 1, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	matches, err := filepath.Glob(vals[0])
+	if err != nil {
+		return object.NewError(object.VALUE_ERROR, "invalid pattern %s", vals[0])
+	}
+
+	sort.Strings(matches)
+
+	return stringArray(matches)
+}
+
+func ExistsFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("exists", args, 1, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	_, err := os.Stat(vals[0])
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fsError(err, "failed to check if %s exists", vals[0])
+	}
+
+	return &object.BooleanObject{Value: err == nil}
+}
+
+// check if a path is a directory, paths that don't exist aren't directories
+func IsDirFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("isDir", args, 1, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	info, err := os.Stat(vals[0])
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fsError(err, "failed to check if %s is a directory", vals[0])
+	}
+
+	return &object.BooleanObject{Value: err == nil && info.IsDir()}
+}
+
+// create a directory along with any parent directories that don't exist, like mkdir -p
+func MkdirFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("mkdir", args, 1, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	if err := os.MkdirAll(vals[0], 0755); err != nil {
+		return fsError(err, "failed to create directory %s", vals[0])
+	}
+
+	return &object.NullObject{}
+}
+
+// remove a file or an empty directory. Passing true as the second argument also removes
+// directories that aren't empty, along with everything in them.
+func RemoveFun(rt *object.Runtime, args ...object.Object) object.Object {
+	recursive := false
+
+	if len(args) == 2 {
+		b, ok := args[1].(*object.BooleanObject)
+		if !ok {
+			return object.NewError(object.TYPE_ERROR, "second argument to remove must be a boolean, got %s", args[1].Type())
+		}
+
+		recursive = b.Value
+		args = args[:1]
+	}
+
+	vals, errObj := stringArgs("remove", args, 1, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	// RemoveAll doesn't fail when the path doesn't exist, which would hide mistyped paths
+	if _, err := os.Lstat(vals[0]); err != nil {
+		return fsError(err, "failed to remove %s", vals[0])
+	}
+
+	remove := os.Remove
+	if recursive {
+		remove = os.RemoveAll
+	}
+
+	if err := remove(vals[0]); err != nil {
+		return fsError(err, "failed to remove %s", vals[0])
+	}
+
+	return &object.NullObject{}
+}
+
+func RenameFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("rename", args, 2, 2)
+	if errObj != nil {
+		return errObj
+	}
+
+	if err := os.Rename(vals[0], vals[1]); err != nil {
+		return fsError(err, "failed to rename %s to %s", vals[0], vals[1])
+	}
+
+	return &object.NullObject{}
+}
+
+// copy the contents and permissions of a file, replacing the destination if it exists
+func CopyFileFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("copyFile", args, 2, 2)
+	if errObj != nil {
+		return errObj
+	}
+
+	// check both paths before the destination is opened, opening it truncates the file
+	srcInfo, err := os.Stat(vals[0])
+	if err != nil {
+		return fsError(err, "failed to copy %s to %s", vals[0], vals[1])
+	}
+
+	if srcInfo.IsDir() {
+		return object.NewError(object.IO_ERROR, "failed to copy %s to %s - %s is a directory", vals[0], vals[1], vals[0])
+	}
+
+	if dstInfo, err := os.Stat(vals[1]); err == nil && os.SameFile(srcInfo, dstInfo) {
+		return object.NewError(object.IO_ERROR, "failed to copy %s to %s - they are the same file", vals[0], vals[1])
+	}
+
+	if err := copyFile(vals[0], vals[1], srcInfo.Mode().Perm()); err != nil {
+		return fsError(err, "failed to copy %s to %s", vals[0], vals[1])
+	}
+
+	return &object.NullObject{}
+}
+
+func copyFile(src string, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// get information about a file as a map with its name, size in bytes, modification time in seconds
+// since the Unix epoch, mode (e.g. -rw-r--r--) and whether it's a directory
+func StatFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("stat", args, 1, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	info, err := os.Stat(vals[0])
+	if err != nil {
+		return fsError(err, "failed to stat %s", vals[0])
+	}
+
+	res := object.NewMapObject()
+	res.Set(&object.StringObject{Value: "name"}, &object.StringObject{Value: info.Name()})
+	res.Set(&object.StringObject{Value: "size"}, &object.IntegerObject{Value: info.Size()})
+	res.Set(&object.StringObject{Value: "mtime"}, &object.IntegerObject{Value: info.ModTime().Unix()})
+	res.Set(&object.StringObject{Value: "mode"}, &object.StringObject{Value: info.Mode().String()})
+	res.Set(&object.StringObject{Value: "isDir"}, &object.BooleanObject{Value: info.IsDir()})
+
+	return res
+}
+
+// create an empty file in the temporary directory and return its path. The name is made from
+// an optional pattern, with a random string replacing the last * or added to the end.
+func TempFileFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("tempFile", args, 0, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	pattern := ""
+	if len(vals) == 1 {
+		pattern = vals[0]
+	}
+
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return fsError(err, "failed to create temporary file")
+	}
+
+	if err := f.Close(); err != nil {
+		return fsError(err, "failed to create temporary file")
+	}
+
+	return &object.StringObject{Value: f.Name()}
+}
+
+// create a new directory in the temporary directory and return its path, the name is made the same way as tempFile
+func TempDirFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("tempDir", args, 0, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	pattern := ""
+	if len(vals) == 1 {
+		pattern = vals[0]
+	}
+
+	dir, err := os.MkdirTemp("", pattern)
+	if err != nil {
+		return fsError(err, "failed to create temporary directory")
+	}
+
+	return &object.StringObject{Value: dir}
+}
+
+/*
+--------------------------------------
+path operations
+--------------------------------------
+*/
+
+// join any number of path elements with the separator for the OS
+func JoinFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("join", args, 1, -1)
+	if errObj != nil {
+		return errObj
+	}
+
+	return &object.StringObject{Value: filepath.Join(vals...)}
+}
+
+// get the last element of a path, e.g. base("logs/app.txt") is "app.txt"
+func BaseFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("base", args, 1, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	return &object.StringObject{Value: filepath.Base(vals[0])}
+}
+
+// get everything but the last element of a path, e.g. dir("logs/app.txt") is "logs"
+func DirFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("dir", args, 1, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	return &object.StringObject{Value: filepath.Dir(vals[0])}
+}
+
+// get the extension of a path including the dot, e.g. ext("logs/app.txt") is ".txt"
+func ExtFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("ext", args, 1, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	return &object.StringObject{Value: filepath.Ext(vals[0])}
+}
+
+// get the absolute form of a path, relative paths are resolved from the working directory
+func AbsFun(rt *object.Runtime, args ...object.Object) object.Object {
+	vals, errObj := stringArgs("abs", args, 1, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	path, err := filepath.Abs(vals[0])
+	if err != nil {
+		return fsError(err, "failed to get absolute path of %s", vals[0])
+	}
+
+	return &object.StringObject{Value: path}
+}
@@ -1,117 +1,247 @@
 package stdlib
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/MarkyMan4/yetti/object"
 )
 
-type BuiltIn func(args ...object.Object) object.Object
+// builtins receive the runtime of the program calling them, which has the streams to use for input and output
+type BuiltIn func(rt *object.Runtime, args ...object.Object) object.Object
 
 var BuiltInFuns = map[string]BuiltIn{
-	"print":    PrintFun,
-	"substr":   SubstringFun,
-	"length":   LengthFun,
-	"append":   ArrayAppendFun,
-	"string":   StringFun,
-	"input":    InputFun,
-	"openFile": OpenFileFun,
-	"readFile": ReadFileFun,
+	"print":     PrintFun,
+	"substr":    SubstringFun,
+	"length":    LengthFun,
+	"append":    ArrayAppendFun,
+	"string":    StringFun,
+	"input":     InputFun,
+	"readLine":  ReadLineFun,
+	"readAll":   ReadAllFun,
+	"lines":     LinesFun,
+	"eprint":    EPrintFun,
+	"openFile":  OpenFileFun,
+	"readFile":  ReadFileFun,
+	"write":     WriteFun,
+	"writeLine": WriteLineFun,
+	"seek":      SeekFun,
+	"flush":     FlushFun,
+	"close":     CloseFun,
+	"listDir":   ListDirFun,
+	"walk":      WalkFun,
+	"glob":      GlobFun,
+	"exists":    ExistsFun,
+	"isDir":     IsDirFun,
+	"mkdir":     MkdirFun,
+	"remove":    RemoveFun,
+	"rename":    RenameFun,
+	"copyFile":  CopyFileFun,
+	"stat":      StatFun,
+	"tempFile":  TempFileFun,
+	"tempDir":   TempDirFun,
+	"join":      JoinFun,
+	"base":      BaseFun,
+	"dir":       DirFun,
+	"ext":       ExtFun,
+	"abs":       AbsFun,
+	"keys":      KeysFun,
+	"values":    ValuesFun,
+	"has":       HasFun,
+	"delete":    DeleteFun,
+	"args":      ArgsFun,
+	"getenv":    GetenvFun,
+	"setenv":    SetenvFun,
+	"environ":   EnvironFun,
+	"exit":      ExitFun,
 }
 
-func PrintFun(args ...object.Object) object.Object {
+func PrintFun(rt *object.Runtime, args ...object.Object) object.Object {
+	printArgs(rt.Stdout, args)
+
+	return &object.NullObject{}
+}
+
+// same as print, but writes to stderr
+func EPrintFun(rt *object.Runtime, args ...object.Object) object.Object {
+	printArgs(rt.Stderr, args)
+
+	return &object.NullObject{}
+}
+
+func printArgs(w io.Writer, args []object.Object) {
 	// print each argument separated by space and ending with a newline
 	for i := range args {
-		fmt.Print(args[i].ToString())
+		fmt.Fprint(w, args[i].ToString())
 
 		if i == len(args)-1 {
-			fmt.Println()
+			fmt.Fprintln(w)
 		} else {
-			fmt.Print(" ")
+			fmt.Fprint(w, " ")
 		}
 	}
-
-	return &object.NullObject{}
 }
 
-func InputFun(args ...object.Object) object.Object {
+func InputFun(rt *object.Runtime, args ...object.Object) object.Object {
 	if len(args) > 1 {
-		return &object.ErrorObject{Message: fmt.Sprintf("input expects 0 or 1 arguments but received %d", len(args))}
+		return object.NewError(object.ARGUMENT_ERROR, "input expects 0 or 1 arguments but received %d", len(args))
 	}
 
 	if len(args) > 0 {
 		if args[0].Type() != object.STRING_OBJ {
-			return &object.ErrorObject{Message: fmt.Sprintf("input expects string argument but received object of type %s", args[0].Type())}
+			return object.NewError(object.TYPE_ERROR, "input expects string argument but received object of type %s", args[0].Type())
 		}
 
-		fmt.Print(args[0].ToString())
+		fmt.Fprint(rt.Stdout, args[0].ToString())
+	}
+
+	// input gives an empty string at the end of input, use readLine to tell the difference
+	line, _, err := rt.ReadLine()
+	if err != nil {
+		return object.NewError(object.IO_ERROR, "failed to read input - %s", err.Error())
 	}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
+	return &object.StringObject{Value: line}
+}
 
-	return &object.StringObject{Value: scanner.Text()}
+// where readLine, readAll and lines read from
+type lineReader interface {
+	ReadLine() (string, bool, error)
+	ReadAll() (string, error)
 }
 
-// TODO: handle indices out of bounds or invalid indices (e.g. index 2 < index 1)
-func SubstringFun(args ...object.Object) object.Object {
-	if args[0].Type() != object.STRING_OBJ {
-		return &object.ErrorObject{Message: fmt.Sprintf("object of type %s has no function substring", args[0].Type())}
+// input is read from stdin, unless the function is called on a file, e.g. f.readLine()
+// the description of where the input comes from is used in error messages
+func inputSource(rt *object.Runtime, fnName string, args []object.Object) (lineReader, string, *object.ErrorObject) {
+	if len(args) == 0 {
+		return rt, "input", nil
+	}
+
+	if file, ok := args[0].(*object.FileObject); ok && len(args) == 1 {
+		return file, "file " + file.FileName, nil
+	}
+
+	return nil, "", object.NewError(object.ARGUMENT_ERROR, "%s takes no arguments, or a file to read from", fnName)
+}
+
+// read the next line without its line ending, or null if there is nothing left to read
+func ReadLineFun(rt *object.Runtime, args ...object.Object) object.Object {
+	src, desc, errObj := inputSource(rt, "readLine", args)
+	if errObj != nil {
+		return errObj
+	}
+
+	line, ok, err := src.ReadLine()
+	if err != nil {
+		return object.NewError(object.IO_ERROR, "failed to read %s - %s", desc, err.Error())
 	}
 
+	if !ok {
+		return &object.NullObject{}
+	}
+
+	return &object.StringObject{Value: line}
+}
+
+// read everything that is left
+func ReadAllFun(rt *object.Runtime, args ...object.Object) object.Object {
+	src, desc, errObj := inputSource(rt, "readAll", args)
+	if errObj != nil {
+		return errObj
+	}
+
+	content, err := src.ReadAll()
+	if err != nil {
+		return object.NewError(object.IO_ERROR, "failed to read %s - %s", desc, err.Error())
+	}
+
+	return &object.StringObject{Value: content}
+}
+
+// iterate over the lines that are left, e.g. for(line in lines()) { ... }. Each line is read when
+// the loop gets to it, so a script can handle input as it arrives instead of waiting for all of it
+func LinesFun(rt *object.Runtime, args ...object.Object) object.Object {
+	src, desc, errObj := inputSource(rt, "lines", args)
+	if errObj != nil {
+		return errObj
+	}
+
+	return &object.IteratorObject{Next: func() (object.Object, bool, *object.ErrorObject) {
+		line, ok, err := src.ReadLine()
+		if err != nil {
+			return nil, false, object.NewError(object.IO_ERROR, "failed to read %s - %s", desc, err.Error())
+		}
+
+		if !ok {
+			return nil, false, nil
+		}
+
+		return &object.StringObject{Value: line}, true, nil
+	}}
+}
+
+func SubstringFun(rt *object.Runtime, args ...object.Object) object.Object {
 	if len(args) < 2 || len(args) > 3 {
-		return &object.ErrorObject{Message: "must provide one or two arguments to substring function"}
+		return object.NewError(object.ARGUMENT_ERROR, "must provide one or two arguments to substring function")
+	}
+
+	if args[0].Type() != object.STRING_OBJ {
+		return object.NewError(object.TYPE_ERROR, "object of type %s has no function substring", args[0].Type())
 	}
 
-	if len(args) >= 2 && args[1].Type() != object.INTEGER_OBJ {
-		return &object.ErrorObject{Message: "arguments must be integers"}
+	if args[1].Type() != object.INTEGER_OBJ {
+		return object.NewError(object.TYPE_ERROR, "arguments must be integers")
 	}
 
-	if len(args) == 2 && args[2].Type() != object.INTEGER_OBJ {
-		return &object.ErrorObject{Message: "arguments must be integers"}
+	if len(args) == 3 && args[2].Type() != object.INTEGER_OBJ {
+		return object.NewError(object.TYPE_ERROR, "arguments must be integers")
 	}
 
 	strLit := args[0].(*object.StringObject).Value
 	startIdx := args[1].(*object.IntegerObject).Value
+	endIdx := int64(len(strLit))
 
-	if len(args) == 2 {
-		return &object.StringObject{Value: strLit[startIdx:]}
+	if len(args) == 3 {
+		endIdx = args[2].(*object.IntegerObject).Value
 	}
 
-	endIdx := args[2].(*object.IntegerObject).Value
+	if startIdx < 0 || endIdx > int64(len(strLit)) || startIdx > endIdx {
+		return object.NewError(object.INDEX_ERROR, "substring indices %d:%d out of bounds for string of length %d", startIdx, endIdx, len(strLit))
+	}
 
 	return &object.StringObject{Value: strLit[startIdx:endIdx]}
 }
 
-// get length of string or array
-func LengthFun(args ...object.Object) object.Object {
-	if args[0].Type() != object.STRING_OBJ && args[0].Type() != object.ARRAY_OBJ {
-		return &object.ErrorObject{Message: fmt.Sprintf("object of type %s has no function length", args[0].Type())}
-	}
-
-	if len(args) > 1 {
-		return &object.ErrorObject{Message: "length function takes no arguments"}
+// get length of string, array or map
+func LengthFun(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return object.NewError(object.ARGUMENT_ERROR, "length function takes no arguments")
 	}
 
-	if args[0].Type() == object.STRING_OBJ {
-		strObj := args[0].(*object.StringObject)
-		return &object.IntegerObject{Value: int64(len(strObj.Value))}
-	} else {
-		arrObj := args[0].(*object.ArrayObject)
-		return &object.IntegerObject{Value: int64(len(arrObj.Items))}
+	switch obj := args[0].(type) {
+	case *object.StringObject:
+		return &object.IntegerObject{Value: int64(len(obj.Value))}
+	case *object.ArrayObject:
+		return &object.IntegerObject{Value: int64(len(obj.Items))}
+	case *object.MapObject:
+		return &object.IntegerObject{Value: int64(len(obj.Keys))}
+	default:
+		return object.NewError(object.TYPE_ERROR, "object of type %s has no function length", args[0].Type())
 	}
 }
 
 // append to an array
-func ArrayAppendFun(args ...object.Object) object.Object {
-	if args[0].Type() != object.ARRAY_OBJ {
-		return &object.ErrorObject{Message: fmt.Sprintf("object of type %s has no function append", args[0].Type())}
+func ArrayAppendFun(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError(object.ARGUMENT_ERROR, "append takes exactly one argument")
 	}
 
-	if len(args) != 2 {
-		return &object.ErrorObject{Message: "append takes exactly one argument"}
+	if args[0].Type() != object.ARRAY_OBJ {
+		return object.NewError(object.TYPE_ERROR, "object of type %s has no function append", args[0].Type())
 	}
 
 	arr := args[0].(*object.ArrayObject)
@@ -121,50 +251,330 @@ func ArrayAppendFun(args ...object.Object) object.Object {
 }
 
 // convert object to string object
-func StringFun(args ...object.Object) object.Object {
+func StringFun(rt *object.Runtime, args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return &object.ErrorObject{Message: "string takes exactly one argument"}
+		return object.NewError(object.ARGUMENT_ERROR, "string takes exactly one argument")
 	}
 
 	return &object.StringObject{Value: args[0].ToString()}
 }
 
+/*
+--------------------------------------
+map operations
+--------------------------------------
+*/
+
+// get the keys of a map as an array, in the order they were inserted
+func KeysFun(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 || args[0].Type() != object.MAP_OBJ {
+		return object.NewError(object.ARGUMENT_ERROR, "keys takes exactly one map argument")
+	}
+
+	mapObj := args[0].(*object.MapObject)
+	keys := &object.ArrayObject{Items: []object.Object{}}
+
+	for _, hashKey := range mapObj.Keys {
+		keys.Items = append(keys.Items, mapObj.Pairs[hashKey].Key)
+	}
+
+	return keys
+}
+
+// get the values of a map as an array, in the order their keys were inserted
+func ValuesFun(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 || args[0].Type() != object.MAP_OBJ {
+		return object.NewError(object.ARGUMENT_ERROR, "values takes exactly one map argument")
+	}
+
+	mapObj := args[0].(*object.MapObject)
+	values := &object.ArrayObject{Items: []object.Object{}}
+
+	for _, hashKey := range mapObj.Keys {
+		values.Items = append(values.Items, mapObj.Pairs[hashKey].Value)
+	}
+
+	return values
+}
+
+// check if a map contains a key
+func HasFun(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 || args[0].Type() != object.MAP_OBJ {
+		return object.NewError(object.ARGUMENT_ERROR, "has takes a map and a key")
+	}
+
+	key, ok := args[1].(object.Hashable)
+	if !ok {
+		return object.NewError(object.TYPE_ERROR, "cannot use object of type %s as map key", args[1].Type())
+	}
+
+	_, found := args[0].(*object.MapObject).Get(key)
+
+	return &object.BooleanObject{Value: found}
+}
+
+// remove a key from a map, the map is modified in place and returned
+func DeleteFun(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 || args[0].Type() != object.MAP_OBJ {
+		return object.NewError(object.ARGUMENT_ERROR, "delete takes a map and a key")
+	}
+
+	key, ok := args[1].(object.Hashable)
+	if !ok {
+		return object.NewError(object.TYPE_ERROR, "cannot use object of type %s as map key", args[1].Type())
+	}
+
+	mapObj := args[0].(*object.MapObject)
+	mapObj.Delete(key)
+
+	return mapObj
+}
+
 /*
 --------------------------------------
 file operations
 --------------------------------------
 */
-func OpenFileFun(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return &object.ErrorObject{Message: "openFile takes exactly one arguments"}
+// open a file in one of the modes r, w, a, r+, w+ or a+, files are opened for reading if no mode is given
+func OpenFileFun(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return object.NewError(object.ARGUMENT_ERROR, "openFile takes a file name and an optional mode")
 	}
 
 	if args[0].Type() != object.STRING_OBJ {
-		return &object.ErrorObject{Message: "argument must be a file name"}
+		return object.NewError(object.TYPE_ERROR, "argument must be a file name")
 	}
 
-	if _, err := os.Stat(args[0].ToString()); err != nil {
-		return &object.ErrorObject{Message: fmt.Sprintf("file %s does not exist", args[0].ToString())}
+	mode := "r"
+
+	if len(args) == 2 {
+		if args[1].Type() != object.STRING_OBJ {
+			return object.NewError(object.TYPE_ERROR, "mode must be a string, got %s", args[1].Type())
+		}
+
+		mode = args[1].ToString()
 	}
 
-	return &object.FileObject{FileName: args[0].ToString()}
+	file, err := object.OpenFile(args[0].ToString(), mode)
+	if errors.Is(err, object.ErrUnknownFileMode) {
+		return object.NewError(object.VALUE_ERROR, "failed to open file %s - %s", args[0].ToString(), err.Error())
+	} else if err != nil {
+		return fsError(err, "failed to open file %s", args[0].ToString())
+	}
+
+	// files the program doesn't close are closed when it ends
+	rt.AddFile(file)
+
+	return file
 }
 
-func ReadFileFun(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return &object.ErrorObject{Message: "readFile must use a file object"}
+// read from the current position of a file to the end
+func ReadFileFun(rt *object.Runtime, args ...object.Object) object.Object {
+	file, errObj := fileArg("readFile", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	content, err := file.ReadAll()
+	if err != nil {
+		return object.NewError(object.IO_ERROR, "failed to read file %s - %s", file.FileName, err.Error())
+	}
+
+	return &object.StringObject{Value: content}
+}
+
+// write the string form of a value to a file
+func WriteFun(rt *object.Runtime, args ...object.Object) object.Object {
+	return writeFile("write", args, "")
+}
+
+// write the string form of a value to a file followed by a newline
+func WriteLineFun(rt *object.Runtime, args ...object.Object) object.Object {
+	return writeFile("writeLine", args, "\n")
+}
+
+func writeFile(fnName string, args []object.Object, end string) object.Object {
+	file, errObj := fileArg(fnName, args, 2)
+	if errObj != nil {
+		return errObj
+	}
+
+	if err := file.Write(args[1].ToString() + end); err != nil {
+		return object.NewError(object.IO_ERROR, "failed to write file %s - %s", file.FileName, err.Error())
+	}
+
+	return &object.NullObject{}
+}
+
+// move to a position in a file and return it. The offset is from the start of the file, or if whence is given
+// it is from the start (0), the current position (1) or the end of the file (2)
+func SeekFun(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) == 2 {
+		args = append(args, &object.IntegerObject{Value: io.SeekStart})
 	}
 
-	if args[0].Type() != object.FILE_OBJ {
-		return &object.ErrorObject{Message: fmt.Sprintf("readFile is not defined for object of type %s", args[0].Type())}
+	file, errObj := fileArg("seek", args, 3)
+	if errObj != nil {
+		return errObj
 	}
 
-	filename := args[0].(*object.FileObject).FileName
-	content, err := os.ReadFile(filename)
+	offset, ok := args[1].(*object.IntegerObject)
+	if !ok {
+		return object.NewError(object.TYPE_ERROR, "offset must be an integer, got %s", args[1].Type())
+	}
 
+	whence, ok := args[2].(*object.IntegerObject)
+	if !ok || whence.Value < io.SeekStart || whence.Value > io.SeekEnd {
+		return object.NewError(object.VALUE_ERROR, "whence must be 0, 1 or 2")
+	}
+
+	pos, err := file.Seek(offset.Value, int(whence.Value))
 	if err != nil {
-		return &object.ErrorObject{Message: fmt.Sprintf("failed to read file %s - %s", filename, err.Error())}
+		return object.NewError(object.IO_ERROR, "failed to seek in file %s - %s", file.FileName, err.Error())
+	}
+
+	return &object.IntegerObject{Value: pos}
+}
+
+// write anything buffered for a file
+func FlushFun(rt *object.Runtime, args ...object.Object) object.Object {
+	file, errObj := fileArg("flush", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	if err := file.Flush(); err != nil {
+		return object.NewError(object.IO_ERROR, "failed to flush file %s - %s", file.FileName, err.Error())
+	}
+
+	return &object.NullObject{}
+}
+
+func CloseFun(rt *object.Runtime, args ...object.Object) object.Object {
+	file, errObj := fileArg("close", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	if err := file.Close(); err != nil {
+		return object.NewError(object.IO_ERROR, "failed to close file %s - %s", file.FileName, err.Error())
+	}
+
+	return &object.NullObject{}
+}
+
+// check a file function was called on a file with the right number of arguments, including the file
+func fileArg(fnName string, args []object.Object, numArgs int) (*object.FileObject, *object.ErrorObject) {
+	if len(args) == 0 {
+		return nil, object.NewError(object.ARGUMENT_ERROR, "%s must be called on a file", fnName)
+	}
+
+	file, ok := args[0].(*object.FileObject)
+	if !ok {
+		return nil, object.NewError(object.TYPE_ERROR, "%s is not defined for object of type %s", fnName, args[0].Type())
+	}
+
+	if len(args) != numArgs {
+		return nil, object.NewError(object.ARGUMENT_ERROR, "%s expects %d arguments but received %d", fnName, numArgs-1, len(args)-1)
+	}
+
+	return file, nil
+}
+
+/*
+--------------------------------------
+process operations
+--------------------------------------
+*/
+
+// get the command-line arguments given to the program as an array of strings
+func ArgsFun(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return object.NewError(object.ARGUMENT_ERROR, "args takes no arguments")
+	}
+
+	res := &object.ArrayObject{Items: []object.Object{}}
+
+	for _, arg := range rt.Args {
+		res.Items = append(res.Items, &object.StringObject{Value: arg})
+	}
+
+	return res
+}
+
+// get the value of an environment variable, or null if it isn't set
+func GetenvFun(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 || args[0].Type() != object.STRING_OBJ {
+		return object.NewError(object.ARGUMENT_ERROR, "getenv takes exactly one string argument")
+	}
+
+	val, ok := os.LookupEnv(args[0].ToString())
+	if !ok {
+		return &object.NullObject{}
+	}
+
+	return &object.StringObject{Value: val}
+}
+
+// set an environment variable for the rest of the program and any processes it starts
+func SetenvFun(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 || args[0].Type() != object.STRING_OBJ || args[1].Type() != object.STRING_OBJ {
+		return object.NewError(object.ARGUMENT_ERROR, "setenv takes a name and a value, both must be strings")
+	}
+
+	if err := os.Setenv(args[0].ToString(), args[1].ToString()); err != nil {
+		return object.NewError(object.VALUE_ERROR, "failed to set environment variable %s - %s", args[0].ToString(), err.Error())
+	}
+
+	return &object.NullObject{}
+}
+
+// get all environment variables as a map, sorted by name
+func EnvironFun(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return object.NewError(object.ARGUMENT_ERROR, "environ takes no arguments")
+	}
+
+	vals := map[string]string{}
+	names := []string{}
+
+	for _, v := range os.Environ() {
+		name, val, _ := strings.Cut(v, "=")
+		vals[name] = val
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	res := object.NewMapObject()
+
+	for _, name := range names {
+		res.Set(&object.StringObject{Value: name}, &object.StringObject{Value: vals[name]})
+	}
+
+	return res
+}
+
+// end the program with an exit status, 0 if one isn't given. Exiting can't be caught by try blocks.
+func ExitFun(rt *object.Runtime, args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return object.NewError(object.ARGUMENT_ERROR, "exit expects 0 or 1 arguments but received %d", len(args))
+	}
+
+	code := int64(0)
+
+	if len(args) == 1 {
+		codeObj, ok := args[0].(*object.IntegerObject)
+		if !ok {
+			return object.NewError(object.TYPE_ERROR, "exit status must be an integer, got %s", args[0].Type())
+		}
+
+		code = codeObj.Value
+	}
+
+	// the operating system only keeps the low byte of the status, so 256 would look like success
+	if code < 0 || code > 255 {
+		return object.NewError(object.VALUE_ERROR, "exit status must be between 0 and 255, got %d", code)
 	}
 
-	return &object.StringObject{Value: string(content)}
+	return object.NewExit(int(code))
 }
@@ -1,58 +1,109 @@
 package token
 
+import "fmt"
+
+// location in the source code, lines and columns are counted from 1
+type Position struct {
+	Line int
+	Col  int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
+// a position of 0:0 means the position is unknown, e.g. for nodes that weren't created by the parser
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
 type Token struct {
 	Type    string
 	Literal string
+	Pos     Position
+	Parts   []StringPart // pieces of an interpolated string, only set for INTERP_STRING tokens
+}
+
+// piece of an interpolated string, either text or the source of an embedded expression, e.g. "n is ${n + 1}"
+type StringPart struct {
+	Text   string
+	IsExpr bool
+	Pos    Position // where the expression's source starts
 }
 
 const (
-	VAR     = "VAR"
-	FOR     = "FOR"
-	WHILE   = "WHILE"
-	IF      = "IF"
-	ELSE    = "ELSE"
-	FUN     = "FUN"
-	RETURN  = "RETURN"
-	PLUS    = "+"
-	MINUS   = "-"
-	MULT    = "*"
-	DIVIDE  = "/"
-	LT      = "<"
-	LTE     = "<="
-	EQ      = "=="
-	GT      = ">"
-	GTE     = ">="
-	ASSIGN  = "="
-	PLUSEQ  = "+="
-	MINEQ   = "-="
-	MULTEQ  = "*="
-	DIVEQ   = "/="
-	LPAREN  = "("
-	RPAREN  = ")"
-	LBRACE  = "{"
-	RBRACE  = "}"
-	LBRACK  = "["
-	RBRACK  = "]"
-	SEMI    = ";"
-	COM     = ","
-	DQUOTE  = "\""
-	DOT     = "."
-	IDENT   = "IDENT"
-	INT     = "INT"
-	FLOAT   = "FLOAT"
-	STRING  = "STRING"
-	BOOLEAN = "BOOLEAN"
-	EOF     = "EOF"
+	VAR           = "VAR"
+	FOR           = "FOR"
+	WHILE         = "WHILE"
+	IF            = "IF"
+	ELSE          = "ELSE"
+	FUN           = "FUN"
+	RETURN        = "RETURN"
+	IN            = "IN"
+	BREAK         = "BREAK"
+	CONTINUE      = "CONTINUE"
+	THROW         = "THROW"
+	TRY           = "TRY"
+	CATCH         = "CATCH"
+	IMPORT        = "IMPORT"
+	AS            = "AS"
+	PLUS          = "+"
+	MINUS         = "-"
+	MULT          = "*"
+	DIVIDE        = "/"
+	LT            = "<"
+	LTE           = "<="
+	EQ            = "=="
+	NEQ           = "!="
+	AND           = "&&"
+	OR            = "||"
+	BANG          = "!"
+	GT            = ">"
+	GTE           = ">="
+	ASSIGN        = "="
+	PLUSEQ        = "+="
+	MINEQ         = "-="
+	MULTEQ        = "*="
+	DIVEQ         = "/="
+	LPAREN        = "("
+	RPAREN        = ")"
+	LBRACE        = "{"
+	RBRACE        = "}"
+	LBRACK        = "["
+	RBRACK        = "]"
+	SEMI          = ";"
+	COM           = ","
+	COLON         = ":"
+	DQUOTE        = "\""
+	DOT           = "."
+	IDENT         = "IDENT"
+	INT           = "INT"
+	FLOAT         = "FLOAT"
+	STRING        = "STRING"
+	INTERP_STRING = "INTERP_STRING"
+	BOOLEAN       = "BOOLEAN"
+	NULL          = "NULL"
+	EOF           = "EOF"
+	ILLEGAL       = "ILLEGAL" // a character or string the lexer doesn't understand
 )
 
 var keywords = map[string]string{
-	"var":    VAR,
-	"for":    FOR,
-	"while":  WHILE,
-	"if":     IF,
-	"else":   ELSE,
-	"fun":    FUN,
-	"return": RETURN,
+	"var":      VAR,
+	"for":      FOR,
+	"while":    WHILE,
+	"if":       IF,
+	"else":     ELSE,
+	"fun":      FUN,
+	"return":   RETURN,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"throw":    THROW,
+	"try":      TRY,
+	"catch":    CATCH,
+	"import":   IMPORT,
+	"as":       AS,
+	"null":     NULL,
 }
 
 // lookup a value from the input and determine if it is a keyword or an identifier
@@ -0,0 +1,117 @@
+package vm
+
+import (
+	"github.com/MarkyMan4/yetti/compiler"
+	"github.com/MarkyMan4/yetti/object"
+	"github.com/MarkyMan4/yetti/stdlib"
+)
+
+// function created by running a function definition, it keeps the scope it was defined in
+type Closure struct {
+	Fn    *compiler.CompiledFunction
+	Scope *Scope
+}
+
+func (c *Closure) Type() string {
+	return object.FUNCTION_OBJ
+}
+
+func (c *Closure) ToString() string {
+	return "function"
+}
+
+// variables of a scope at runtime, a slot is nil until its variable has been defined
+type Scope struct {
+	slots  []object.Object
+	names  []string
+	parent *Scope
+}
+
+func NewScope(info *compiler.Scope, parent *Scope) *Scope {
+	return &Scope{slots: make([]object.Object, len(info.Names)), names: info.Names, parent: parent}
+}
+
+// get a variable defined in this scope by name, this is how the members of a module are looked up
+func (s *Scope) Get(ident string) (object.Object, bool) {
+	if slot := s.slotOf(ident); slot >= 0 {
+		return s.slots[slot], true
+	}
+
+	return nil, false
+}
+
+// slot of a variable that has been defined in this scope, -1 if there isn't one
+func (s *Scope) slotOf(ident string) int {
+	// arguments with the same name have separate slots, the last one is the one that's used
+	for i := len(s.names) - 1; i >= 0; i-- {
+		if s.names[i] == ident && s.slots[i] != nil {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (s *Scope) up(depth int) *Scope {
+	scope := s
+	for i := 0; i < depth; i++ {
+		scope = scope.parent
+	}
+
+	return scope
+}
+
+func (s *Scope) name(depth int, slot int) string {
+	return s.up(depth).names[slot]
+}
+
+// get the variable in a slot. If it hasn't been defined yet, the variable is looked up by name in the
+// scopes above it, the same way the evaluator would find it.
+func (s *Scope) get(depth int, slot int) (object.Object, bool) {
+	scope := s.up(depth)
+	if val := scope.slots[slot]; val != nil {
+		return val, true
+	}
+
+	if found, foundSlot := scope.lookup(scope.names[slot]); found != nil {
+		return found.slots[foundSlot], true
+	}
+
+	return nil, false
+}
+
+// assign to a variable, in the scope above the slot it was found in if the slot hasn't been defined
+func (s *Scope) set(depth int, slot int, val object.Object) {
+	scope := s.up(depth)
+	if scope.slots[slot] == nil {
+		if found, foundSlot := scope.lookup(scope.names[slot]); found != nil {
+			found.slots[foundSlot] = val
+			return
+		}
+	}
+
+	scope.slots[slot] = val
+}
+
+// find the closest scope above this one that has the variable defined
+func (s *Scope) lookup(ident string) (*Scope, int) {
+	for scope := s.parent; scope != nil; scope = scope.parent {
+		if slot := scope.slotOf(ident); slot >= 0 {
+			return scope, slot
+		}
+	}
+
+	return nil, -1
+}
+
+type builtin struct {
+	fn stdlib.BuiltIn
+}
+
+func (b *builtin) Type() string {
+	return object.FUNCTION_OBJ
+}
+
+func (b *builtin) ToString() string {
+	return "function"
+}
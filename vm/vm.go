@@ -0,0 +1,516 @@
+package vm
+
+import (
+	"os"
+	"strings"
+
+	"github.com/MarkyMan4/yetti/compiler"
+	"github.com/MarkyMan4/yetti/evaluator"
+	"github.com/MarkyMan4/yetti/lexer"
+	"github.com/MarkyMan4/yetti/object"
+	"github.com/MarkyMan4/yetti/parser"
+	"github.com/MarkyMan4/yetti/stdlib"
+)
+
+var (
+	nullObj  = &object.NullObject{}
+	trueObj  = &object.BooleanObject{Value: true}
+	falseObj = &object.BooleanObject{Value: false}
+)
+
+type frame struct {
+	closure *Closure
+	ip      int
+	base    int // height of the stack below the function being called, the return value replaces everything above it
+	scope   *Scope
+	module  string // path of the module whose top-level code this frame runs, empty for functions and the main file
+	depth   int    // number of function calls running, including this one
+}
+
+// a try block that is running, errors raised while it runs jump to its catch block
+type handler struct {
+	frame int
+	sp    int
+	scope *Scope
+	catch int
+}
+
+type VM struct {
+	stack    []object.Object
+	frames   []*frame
+	handlers []handler
+	globals  *Scope          // top-level scope of the file being run
+	runtime  *object.Runtime // streams passed to builtins, and the modules that have been imported
+}
+
+func NewVM(runtime *object.Runtime) *VM {
+	return &VM{runtime: runtime}
+}
+
+// run the top-level code of a compiled file, returns the error if one is raised and not caught
+func (vm *VM) Run(bytecode *compiler.Bytecode) *object.ErrorObject {
+	vm.stack = vm.stack[:0]
+	vm.handlers = vm.handlers[:0]
+	vm.globals = NewScope(bytecode.Scopes[bytecode.Main.Scope], nil)
+	vm.frames = []*frame{{closure: &Closure{Fn: bytecode.Main}, scope: vm.globals}}
+
+	// the import stack is shared with anything else using the runtime, so imports that an uncaught
+	// error stopped part way through are taken off it
+	depth := len(vm.runtime.ImportStack)
+	errObj := vm.run()
+	vm.runtime.ImportStack = vm.runtime.ImportStack[:depth]
+
+	return errObj
+}
+
+func (vm *VM) run() *object.ErrorObject {
+	for {
+		f := vm.frames[len(vm.frames)-1]
+		fn := f.closure.Fn
+		ins := fn.Instructions
+		constants := fn.Bytecode.Constants
+		start := f.ip
+		op := compiler.Opcode(ins[f.ip])
+		f.ip++
+
+		var errObj *object.ErrorObject
+
+		switch op {
+		case compiler.OpConstant:
+			vm.push(constants[vm.readUint16(f)])
+		case compiler.OpNull:
+			vm.push(nullObj)
+		case compiler.OpTrue:
+			vm.push(trueObj)
+		case compiler.OpFalse:
+			vm.push(falseObj)
+		case compiler.OpPop:
+			vm.pop()
+		case compiler.OpAdd, compiler.OpSub, compiler.OpMul, compiler.OpDiv, compiler.OpEqual, compiler.OpNotEqual,
+			compiler.OpLess, compiler.OpLessEqual, compiler.OpGreater, compiler.OpGreaterEqual:
+			right := vm.pop()
+			left := vm.pop()
+			errObj = vm.pushResult(evaluator.ApplyInfix(compiler.Operator(op), left, right))
+		case compiler.OpMinus, compiler.OpPlus, compiler.OpNot:
+			errObj = vm.pushResult(evaluator.ApplyPrefix(compiler.Operator(op), vm.pop()))
+		case compiler.OpLogicalLeft:
+			logicalOp := compiler.LogicalOp(vm.readUint8(f))
+			addr := vm.readUint16(f)
+
+			left, err := evaluator.LogicalOperand(logicalOp, "left", vm.pop())
+			if err != nil {
+				errObj = err
+				break
+			}
+
+			// the left side decides the result, so the right side is skipped
+			if (logicalOp == "&&" && !left) || (logicalOp == "||" && left) {
+				vm.push(&object.BooleanObject{Value: left})
+				f.ip = addr
+			}
+		case compiler.OpLogicalRight:
+			logicalOp := compiler.LogicalOp(vm.readUint8(f))
+
+			right, err := evaluator.LogicalOperand(logicalOp, "right", vm.pop())
+			if err != nil {
+				errObj = err
+				break
+			}
+
+			vm.push(&object.BooleanObject{Value: right})
+		case compiler.OpJump:
+			f.ip = vm.readUint16(f)
+		case compiler.OpJumpIfFalse:
+			addr := vm.readUint16(f)
+			cond := vm.pop()
+
+			if errObj = evaluator.CheckCondition(cond); errObj == nil && !cond.(*object.BooleanObject).Value {
+				f.ip = addr
+			}
+		case compiler.OpGetVar:
+			depth, slot := vm.readUint8(f), vm.readUint16(f)
+
+			val, ok := f.scope.get(depth, slot)
+			if !ok {
+				errObj = object.NewError(object.NAME_ERROR, "identifier %s is not defined", f.scope.name(depth, slot))
+				break
+			}
+
+			vm.push(val)
+		case compiler.OpGetAssignTarget:
+			depth, slot := vm.readUint8(f), vm.readUint16(f)
+
+			val, ok := f.scope.get(depth, slot)
+			if !ok {
+				errObj = object.NewError(object.NAME_ERROR, "variable %s has not been declared", f.scope.name(depth, slot))
+				break
+			}
+
+			vm.push(val)
+		case compiler.OpGetFunction:
+			depth, slot := vm.readUint8(f), vm.readUint16(f)
+			name := f.scope.name(depth, slot)
+
+			// variables take precedence over builtins with the same name
+			if val, ok := f.scope.get(depth, slot); ok {
+				if _, ok := val.(*Closure); !ok {
+					errObj = object.NewError(object.TYPE_ERROR, "%s is not a function", name)
+					break
+				}
+
+				vm.push(val)
+			} else if fn, ok := stdlib.BuiltInFuns[name]; ok {
+				vm.push(&builtin{fn: fn})
+			} else {
+				errObj = object.NewError(object.NAME_ERROR, "function %s is not defined", name)
+			}
+		case compiler.OpDefineVar:
+			f.scope.slots[vm.readUint16(f)] = vm.peek(0)
+		case compiler.OpSetVar:
+			assignOp := compiler.AssignOp(vm.readUint8(f))
+			depth, slot := vm.readUint8(f), vm.readUint16(f)
+			right := vm.pop()
+			left := vm.pop()
+
+			val := evaluator.ApplyAssignOp(assignOp, left, right)
+			if errObj = vm.pushResult(val); errObj == nil {
+				f.scope.set(depth, slot, val)
+			}
+		case compiler.OpPushScope:
+			f.scope = NewScope(fn.Bytecode.Scopes[vm.readUint16(f)], f.scope)
+		case compiler.OpPopScope:
+			f.scope = f.scope.parent
+		case compiler.OpArray:
+			n := vm.readUint16(f)
+			items := make([]object.Object, n)
+			copy(items, vm.stack[len(vm.stack)-n:])
+			vm.stack = vm.stack[:len(vm.stack)-n]
+			vm.push(&object.ArrayObject{Items: items})
+		case compiler.OpMap:
+			n := vm.readUint16(f)
+			mapObj := object.NewMapObject()
+			pairs := vm.stack[len(vm.stack)-2*n:]
+
+			for i := 0; i < len(pairs); i += 2 {
+				mapObj.Set(pairs[i].(object.Hashable), pairs[i+1])
+			}
+
+			vm.stack = vm.stack[:len(vm.stack)-2*n]
+			vm.push(mapObj)
+		case compiler.OpCheckMapKey:
+			_, errObj = evaluator.MapKey(vm.peek(0))
+		case compiler.OpIndex:
+			idx := vm.pop()
+			obj := vm.pop()
+			errObj = vm.pushResult(evaluator.Index(obj, idx))
+		case compiler.OpCheckIndexTarget:
+			target := constants[vm.readUint16(f)].ToString()
+			errObj = evaluator.CheckIndexTarget(vm.peek(1), vm.peek(0), target)
+		case compiler.OpSetIndex:
+			assignOp := compiler.AssignOp(vm.readUint8(f))
+			target := constants[vm.readUint16(f)].ToString()
+			right := vm.pop()
+			idx := vm.pop()
+			obj := vm.pop()
+			errObj = vm.pushResult(evaluator.AssignIndex(obj, idx, assignOp, right, target))
+		case compiler.OpGetMember:
+			member := constants[vm.readUint16(f)].ToString()
+			errObj = vm.pushResult(evaluator.MemberOf(vm.pop(), member))
+		case compiler.OpCheckMemberTarget:
+			member := constants[vm.readUint16(f)].ToString()
+			errObj = evaluator.CheckMemberTarget(vm.peek(0), member)
+		case compiler.OpSetMember:
+			assignOp := compiler.AssignOp(vm.readUint8(f))
+			member := constants[vm.readUint16(f)].(*object.StringObject)
+			target := constants[vm.readUint16(f)].ToString()
+			right := vm.pop()
+			obj := vm.pop()
+			errObj = vm.pushResult(evaluator.AssignIndex(obj, member, assignOp, right, target))
+		case compiler.OpInterpolate:
+			n := vm.readUint16(f)
+			var sb strings.Builder
+
+			for _, part := range vm.stack[len(vm.stack)-n:] {
+				sb.WriteString(part.ToString())
+			}
+
+			vm.stack = vm.stack[:len(vm.stack)-n]
+			vm.push(&object.StringObject{Value: sb.String()})
+		case compiler.OpClosure:
+			vm.push(&Closure{Fn: constants[vm.readUint16(f)].(*compiler.CompiledFunction), Scope: f.scope})
+		case compiler.OpCheckFunction:
+			name := constants[vm.readUint16(f)].ToString()
+			if _, ok := vm.peek(0).(*Closure); !ok {
+				errObj = object.NewError(object.TYPE_ERROR, "%s is not a function", name)
+			}
+		case compiler.OpGetMethod:
+			errObj = vm.getMethod(vm.pop(), constants[vm.readUint16(f)].ToString())
+		case compiler.OpCall:
+			numArgs := vm.readUint8(f)
+			name := constants[vm.readUint16(f)].ToString()
+			base := len(vm.stack) - numArgs - 1
+			errObj = vm.call(vm.stack[base], vm.stack[base+1:], base, name)
+		case compiler.OpCallMethod:
+			numArgs := vm.readUint8(f)
+			name := constants[vm.readUint16(f)].ToString()
+			base := len(vm.stack) - numArgs - 2
+
+			// builtins get the object they were called on as their first argument
+			if vm.stack[base+1] == nil {
+				errObj = vm.call(vm.stack[base], vm.stack[base+2:], base, name)
+			} else {
+				errObj = vm.call(vm.stack[base], vm.stack[base+1:], base, name)
+			}
+		case compiler.OpReturn:
+			res := vm.pop()
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.stack = vm.stack[:f.base]
+
+			// try blocks in the function can't catch errors anymore
+			for len(vm.handlers) > 0 && vm.handlers[len(vm.handlers)-1].frame >= len(vm.frames) {
+				vm.handlers = vm.handlers[:len(vm.handlers)-1]
+			}
+
+			if f.module != "" {
+				res = vm.finishImport(f)
+			}
+
+			if len(vm.frames) == 0 {
+				return nil
+			}
+
+			vm.push(res)
+		case compiler.OpIterStart:
+			iter, err := evaluator.Iterate(vm.pop())
+			if err != nil {
+				errObj = err
+				break
+			}
+
+			vm.push(iter)
+		case compiler.OpIterNext:
+			addr := vm.readUint16(f)
+			item, ok, err := vm.peek(0).(*object.IteratorObject).Next()
+
+			if err != nil {
+				errObj = err
+				break
+			}
+
+			if !ok {
+				f.ip = addr
+				break
+			}
+
+			vm.push(item)
+		case compiler.OpTry:
+			vm.handlers = append(vm.handlers, handler{frame: len(vm.frames) - 1, sp: len(vm.stack), scope: f.scope, catch: vm.readUint16(f)})
+		case compiler.OpEndTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case compiler.OpThrow:
+			errObj = evaluator.ThrowValue(vm.pop()).(*object.ErrorObject)
+		case compiler.OpImport:
+			errObj = vm.importModule(constants[vm.readUint16(f)].ToString(), fn.Bytecode.File)
+		}
+
+		if errObj == nil {
+			continue
+		}
+
+		// errors are tagged with the position of the instruction that raised them
+		if pos, ok := fn.Positions[start]; ok && !errObj.Pos.IsValid() {
+			errObj.File = fn.Bytecode.File
+			errObj.Pos = pos
+		}
+
+		if !vm.catch(errObj) {
+			return errObj
+		}
+	}
+}
+
+// push the result of an operation, or return it if it's an error
+func (vm *VM) pushResult(res object.Object) *object.ErrorObject {
+	if errObj, ok := res.(*object.ErrorObject); ok {
+		return errObj
+	}
+
+	vm.push(res)
+
+	return nil
+}
+
+// call a closure or builtin, the function and its arguments start at base on the stack
+func (vm *VM) call(callee object.Object, args []object.Object, base int, name string) *object.ErrorObject {
+	switch callee := callee.(type) {
+	case *Closure:
+		if len(args) != callee.Fn.NumArgs {
+			return object.NewError(object.ARGUMENT_ERROR, "expected %d arguments for function %s, received %d", callee.Fn.NumArgs, name, len(args))
+		}
+
+		depth := vm.frames[len(vm.frames)-1].depth + 1
+		if depth > vm.runtime.MaxCallDepth {
+			return object.NewError(object.RECURSION_ERROR, "maximum recursion depth exceeded")
+		}
+
+		// functions are lexically scoped, so the body runs in a child of the scope the function was defined in
+		scope := NewScope(callee.Fn.Bytecode.Scopes[callee.Fn.Scope], callee.Scope)
+		copy(scope.slots, args)
+		vm.frames = append(vm.frames, &frame{closure: callee, base: base, scope: scope, depth: depth})
+
+		return nil
+	case *builtin:
+		res := callee.fn(vm.runtime, append([]object.Object{}, args...)...)
+		vm.stack = vm.stack[:base]
+
+		return vm.pushResult(res)
+	default:
+		return object.NewError(object.TYPE_ERROR, "%s is not a function", name)
+	}
+}
+
+// push the function to call for obj.name(...), followed by the object if the function is a builtin
+func (vm *VM) getMethod(obj object.Object, name string) *object.ErrorObject {
+	if module, ok := obj.(*object.ModuleObject); ok {
+		member, ok := module.Members.Get(name)
+		if !ok {
+			return object.NewError(object.NAME_ERROR, "module %s has no member %s", module.Path, name)
+		}
+
+		if _, ok := member.(*Closure); !ok {
+			return object.NewError(object.TYPE_ERROR, "%s is not a function", name)
+		}
+
+		vm.push(member)
+		vm.push(nil)
+
+		return nil
+	}
+
+	fn, ok := stdlib.BuiltInFuns[name]
+	if !ok {
+		return object.NewError(object.NAME_ERROR, "function %s is not defined", name)
+	}
+
+	vm.push(&builtin{fn: fn})
+	vm.push(obj)
+
+	return nil
+}
+
+// jump to the catch block of the innermost try block, returns false if there isn't one or the program is exiting
+func (vm *VM) catch(errObj *object.ErrorObject) bool {
+	if len(vm.handlers) == 0 || errObj.Exit {
+		return false
+	}
+
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+	// imports that were running inside the try block are abandoned
+	for _, f := range vm.frames[h.frame+1:] {
+		if f.module != "" {
+			vm.runtime.ImportStack = vm.runtime.ImportStack[:len(vm.runtime.ImportStack)-1]
+		}
+	}
+
+	vm.frames = vm.frames[:h.frame+1]
+	f := vm.frames[h.frame]
+	f.scope = h.scope
+	f.ip = h.catch
+	vm.stack = vm.stack[:h.sp]
+	vm.push(evaluator.CaughtError(errObj))
+
+	return true
+}
+
+// imports are resolved the same way as in the evaluator, the module's top-level code runs in a new frame
+// and the module object is pushed when it returns
+func (vm *VM) importModule(importPath string, importingFile string) *object.ErrorObject {
+	path, errObj := evaluator.ResolveImport(importPath, importingFile, vm.runtime.SearchPath)
+	if errObj != nil {
+		return errObj
+	}
+
+	// modules are cached by the runtime, so each module is only run once per interpreter
+	if module, ok := vm.runtime.Modules[path]; ok {
+		vm.push(module)
+		return nil
+	}
+
+	importStack := vm.runtime.ImportStack
+
+	for i := range importStack {
+		if importStack[i] == path {
+			cycle := append(append([]string{}, importStack[i:]...), path)
+			return object.NewError(object.IMPORT_ERROR, "import cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return object.NewError(object.IMPORT_ERROR, "failed to read module %s - %s", path, err.Error())
+	}
+
+	p := parser.NewParser(lexer.NewLexer(string(src)))
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		return object.NewError(object.IMPORT_ERROR, "failed to parse module %s - %s", path, p.Errors[0])
+	}
+
+	bytecode, compileErr := compiler.NewCompiler(path).Compile(prog)
+	if compileErr != nil {
+		return object.NewError(object.IMPORT_ERROR, "failed to compile module %s - %s", path, compileErr)
+	}
+
+	vm.runtime.ImportStack = append(vm.runtime.ImportStack, path)
+	vm.frames = append(vm.frames, &frame{
+		closure: &Closure{Fn: bytecode.Main},
+		base:    len(vm.stack),
+		scope:   NewScope(bytecode.Scopes[bytecode.Main.Scope], nil),
+		module:  path,
+		depth:   vm.frames[len(vm.frames)-1].depth,
+	})
+
+	return nil
+}
+
+// add a module that finished running to the cache, its members are the variables of its top-level scope
+func (vm *VM) finishImport(f *frame) object.Object {
+	module := &object.ModuleObject{Path: f.module, Members: f.scope}
+	vm.runtime.Modules[f.module] = module
+	vm.runtime.ImportStack = vm.runtime.ImportStack[:len(vm.runtime.ImportStack)-1]
+
+	return module
+}
+
+func (vm *VM) push(obj object.Object) {
+	vm.stack = append(vm.stack, obj)
+}
+
+func (vm *VM) pop() object.Object {
+	obj := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+
+	return obj
+}
+
+// get an item from the top of the stack without removing it, 0 is the top item
+func (vm *VM) peek(n int) object.Object {
+	return vm.stack[len(vm.stack)-1-n]
+}
+
+func (vm *VM) readUint8(f *frame) int {
+	operand := int(f.closure.Fn.Instructions[f.ip])
+	f.ip++
+
+	return operand
+}
+
+func (vm *VM) readUint16(f *frame) int {
+	operand := int(compiler.ReadUint16(f.closure.Fn.Instructions[f.ip:]))
+	f.ip += 2
+
+	return operand
+}
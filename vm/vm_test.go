@@ -0,0 +1,545 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MarkyMan4/yetti/compiler"
+	"github.com/MarkyMan4/yetti/evaluator"
+	"github.com/MarkyMan4/yetti/lexer"
+	"github.com/MarkyMan4/yetti/object"
+	"github.com/MarkyMan4/yetti/parser"
+	"github.com/MarkyMan4/yetti/token"
+)
+
+// run a program with the evaluator and the VM and check they agree on the value of each variable,
+// and on the uncaught error if there is one
+func expectSameResult(t *testing.T, input string, vars ...string) {
+	t.Helper()
+	expectSameResultWithDepth(t, object.DefaultMaxCallDepth, input, vars...)
+}
+
+// same as expectSameResult, with a limit on nested function calls for both
+func expectSameResultWithDepth(t *testing.T, maxDepth int, input string, vars ...string) {
+	t.Helper()
+
+	p := parser.NewParser(lexer.NewLexer(input))
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		t.Fatalf("failed to parse program: %v\n", p.Errors)
+	}
+
+	env := object.NewEnvironment()
+	env.Runtime().MaxCallDepth = maxDepth
+	var evalErr *object.ErrorObject
+
+	for i := range prog.Statements {
+		if errObj, ok := evaluator.Eval(prog.Statements[i], env).(*object.ErrorObject); ok {
+			evalErr = errObj
+			break
+		}
+	}
+
+	prog = parser.NewParser(lexer.NewLexer(input)).Parse()
+	bytecode, compileErr := compiler.NewCompiler("").Compile(prog)
+
+	if compileErr != nil {
+		t.Fatalf("failed to compile program: %v\n", compileErr)
+	}
+
+	rt := object.DefaultRuntime()
+	rt.MaxCallDepth = maxDepth
+	machine := NewVM(rt)
+	vmErr := machine.Run(bytecode)
+
+	if (evalErr == nil) != (vmErr == nil) {
+		t.Fatalf("evaluator error: %v, vm error: %v\n", evalErr, vmErr)
+	}
+
+	if evalErr != nil && (evalErr.ToString() != vmErr.ToString() || evalErr.Pos != vmErr.Pos) {
+		t.Fatalf("expected error %s at %s, got %s at %s\n", evalErr.ToString(), evalErr.Pos, vmErr.ToString(), vmErr.Pos)
+	}
+
+	for _, name := range vars {
+		expected, ok := env.Get(name)
+		if !ok {
+			t.Fatalf("variable %s is not defined by the evaluator\n", name)
+		}
+
+		actual, ok := machine.globals.Get(name)
+		if !ok {
+			t.Fatalf("variable %s is not defined by the vm\n", name)
+		}
+
+		if expected.ToString() != actual.ToString() {
+			t.Errorf("expected %s to be %s, got %s\n", name, expected.ToString(), actual.ToString())
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	expectSameResult(t, `
+		var a = 1 + 2 * 3 - 4;
+		var b = 7 / 2;
+		var c = -a + +2.5;
+		var d = "ab" + "cd";
+		var e = 3 < 4 && !(2 >= 5) || false;
+		var f = a == 3 && d != "x";
+		var g = null;
+		var h = g == null && g != 0;
+	`, "a", "b", "c", "d", "e", "f", "g", "h")
+}
+
+func TestControlFlow(t *testing.T) {
+	expectSameResult(t, `
+		var total = 0;
+		var evens = [];
+
+		for (var i = 0; i < 10; i += 1) {
+			if (i == 7) {
+				break;
+			}
+
+			if (i == 3) {
+				continue;
+			} else if (i == 4) {
+				total += 100;
+			} else {
+				total += i;
+			}
+		}
+
+		var n = 0;
+		while (n < 20) {
+			n += 1;
+			if (n > 10) {
+				break;
+			}
+			if (n == 5) {
+				continue;
+			}
+			total += n;
+		}
+
+		for(x in [1, 2, 3, 4, 5, 6]) {
+			for(y in "ab") {
+				if (y == "b") {
+					break;
+				}
+				if (x == 2) {
+					continue;
+				}
+				evens = append(evens, x * 2);
+			}
+		}
+
+		var keys = [];
+		for(k in {"a": 1, "b": 2}) {
+			keys = append(keys, k);
+		}
+	`, "total", "n", "evens", "keys")
+}
+
+func TestFunctions(t *testing.T) {
+	expectSameResult(t, `
+		fun fib(n) {
+			if (n <= 2) {
+				return 1;
+			}
+
+			return fib(n - 1) + fib(n - 2);
+		}
+
+		fun last(x) {
+			var y = x * 2;
+			y += 1;
+		}
+
+		fun find(xs, target) {
+			for(x in xs) {
+				try {
+					if (x == target) {
+						return "found";
+					}
+				} catch(e) {
+					return "error";
+				}
+			}
+
+			return "missing";
+		}
+
+		fun dup(a, a) {
+			return a;
+		}
+
+		var a = fib(15);
+		var b = last(4);
+		var c = find([1, 2, 3], 2);
+		var d = find([1, 2, 3], 5);
+		var e = dup(1, 2);
+		var f = fun(x) { return x + 1; }(41);
+	`, "a", "b", "c", "d", "e", "f")
+}
+
+func TestClosuresAndScope(t *testing.T) {
+	expectSameResult(t, `
+		fun makeCounter() {
+			var count = 0;
+
+			return fun() {
+				count += 1;
+				return count;
+			};
+		}
+
+		var counter = makeCounter();
+		counter();
+		counter();
+		var a = counter();
+
+		fun useLater() {
+			return later;
+		}
+
+		var later = "defined after the function";
+		var b = useLater();
+
+		// the local isn't defined until the second statement, so the first read finds the global
+		var shadow = "global";
+		fun readShadow() {
+			var first = shadow;
+			var shadow = "local";
+			return first + " " + shadow;
+		}
+
+		var c = readShadow();
+
+		var fns = [];
+		for (var i = 0; i < 3; i += 1) {
+			fns = append(fns, fun() { return i; });
+		}
+
+		var d = fns[0]();
+		var e = 0;
+
+		if (true) {
+			var e = 5;
+		}
+	`, "a", "b", "c", "d", "e")
+}
+
+func TestCollections(t *testing.T) {
+	expectSameResult(t, `
+		var xs = [1, 2, 3];
+		xs[0] = 10;
+		xs[1] += 5;
+
+		var m = {"a": 1, "nested": {"b": [1, 2]}};
+		m["a"] *= 3;
+		m.c = "new";
+		m.nested.b[1] = "two";
+
+		var a = xs;
+		var b = m;
+		var c = m.nested.b[1] + " ${xs[0] + 1} ${m.a}";
+		var d = xs.length();
+
+		var words = ["a", "b", "a"];
+		var counts = {"a": 0, "b": 0};
+		for(var i = 0; i < words.length(); i += 1) {
+			counts[words[i]] += 1;
+		}
+
+		var order = [2, 0];
+		xs[order[1]] = xs[order[0]];
+
+		var e = counts;
+		var f = xs;
+	`, "a", "b", "c", "d", "e", "f")
+}
+
+func TestLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// lines are read as the loop needs them, so the rest of the file is still there after breaking out
+	input := `
+		var f = openFile("` + filepath.ToSlash(path) + `");
+		var first = "";
+
+		for(line in f.lines()) {
+			first = line;
+			break;
+		}
+
+		var next = f.readLine();
+		var rest = [];
+
+		for(line in f.lines()) {
+			rest = append(rest, line);
+		}
+
+		f.close();
+	`
+	expectSameResult(t, input, "first", "next", "rest")
+
+	bytecode, _ := compiler.NewCompiler("").Compile(parser.NewParser(lexer.NewLexer(input)).Parse())
+	machine := NewVM(object.DefaultRuntime())
+
+	if errObj := machine.Run(bytecode); errObj != nil {
+		t.Fatal(errObj.ToString())
+	}
+
+	for name, val := range map[string]string{"first": "one", "next": "two", "rest": "[three]"} {
+		if obj, _ := machine.globals.Get(name); obj == nil || obj.ToString() != val {
+			t.Errorf("expected %s to be %s, got %v\n", name, val, obj)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	expectSameResult(t, `
+		var caught = [];
+
+		try {
+			var x = [1][5];
+		} catch(e) {
+			caught = append(caught, e.kind + ": " + e.message);
+		}
+
+		try {
+			throw {"kind": "ValueError", "message": "bad value"};
+		} catch(e) {
+			caught = append(caught, e.kind + ": " + e.message);
+		}
+
+		fun fails() {
+			return undefinedVar;
+		}
+
+		try {
+			try {
+				fails();
+			} catch(e) {
+				throw e.message;
+			}
+		} catch(e) {
+			caught = append(caught, e.kind + ": " + e.message);
+		}
+
+		var n = 0;
+		while (n < 3) {
+			n += 1;
+
+			try {
+				if (n == 2) {
+					break;
+				}
+			} catch(e) {
+			}
+		}
+
+		try {
+			throw "after the loop";
+		} catch(e) {
+			caught = append(caught, e.message);
+		}
+	`, "caught", "n")
+}
+
+func TestUncaughtErrors(t *testing.T) {
+	tests := []string{
+		`var x = 1 + "a";`,
+		`var x = y;`,
+		`y = 1;`,
+		`nope();`,
+		`var x = 1; x();`,
+		`fun f(a) { return a; } f(1, 2);`,
+		`if (1) { }`,
+		`var x = 5;
+		while (x) { }`,
+		`for(x in 5) { }`,
+		`var m = {}; m.a += 1;`,
+		`var m = {[1]: 2};`,
+		`throw 5;`,
+		`var x = true && 1;`,
+		`var s = "a".nope();`,
+		`try {
+			exit(2);
+		} catch(e) {
+		}`,
+		`fun outer() {
+			fun inner() {
+				return [1, 2][3];
+			}
+
+			return inner();
+		}
+
+		var x = outer();`,
+	}
+
+	for _, input := range tests {
+		expectSameResult(t, input)
+	}
+}
+
+func TestRecursionDepth(t *testing.T) {
+	expectSameResultWithDepth(t, 50, `
+		fun down(n) {
+			if (n == 0) {
+				return 0;
+			}
+			return down(n - 1);
+		}
+
+		var shallow = down(49);
+		var caught = "";
+
+		try {
+			down(50);
+		} catch(e) {
+			caught = e.kind + ": " + e.message;
+		}
+
+		var again = down(49);
+	`, "shallow", "caught", "again")
+
+	expectSameResultWithDepth(t, 50, `
+		fun forever(n) {
+			return forever(n + 1);
+		}
+
+		forever(0);
+	`)
+}
+
+func TestTopLevelReturn(t *testing.T) {
+	// a return outside of a function ends the statement it's in
+	expectSameResult(t, `
+		var a = 0;
+
+		for(x in [1, 2, 3]) {
+			try {
+				a += x;
+				return 1;
+			} catch(e) {
+			}
+		}
+
+		a += 10;
+	`, "a")
+}
+
+func TestErrorPosition(t *testing.T) {
+	prog := parser.NewParser(lexer.NewLexer("var x = 1;\nvar y = x + \"a\";")).Parse()
+
+	bytecode, _ := compiler.NewCompiler("test.yti").Compile(prog)
+	errObj := NewVM(object.DefaultRuntime()).Run(bytecode)
+	if errObj == nil {
+		t.Fatal("expected an error")
+	}
+
+	expected := token.Position{Line: 2, Col: 9}
+	if errObj.Pos != expected || errObj.File != "test.yti" {
+		t.Errorf("expected error at test.yti:%s, got %s:%s\n", expected, errObj.File, errObj.Pos)
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"counter.yti": `
+			var count = 0;
+
+			fun next() {
+				count += 1;
+				return count;
+			}
+		`,
+		"broken.yti": "var x = [1][2];",
+		"a.yti":      `import "b.yti" as b;`,
+		"b.yti":      `import "a.yti" as a;`,
+		"main.yti": `
+			import "counter.yti" as c1;
+			import "counter.yti" as c2;
+
+			c1.next();
+			var a = c2.next();
+			var b = c1.count;
+			var caught = "";
+
+			try {
+				import "broken.yti" as broken;
+			} catch(e) {
+				caught = e.kind;
+			}
+
+			import "a.yti" as cycle;
+		`,
+	}
+
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mainFile := filepath.Join(dir, "main.yti")
+	prog := parser.NewParser(lexer.NewLexer(files["main.yti"])).Parse()
+	rt := object.DefaultRuntime()
+	machine := NewVM(rt)
+	bytecode, _ := compiler.NewCompiler(mainFile).Compile(prog)
+	errObj := machine.Run(bytecode)
+
+	if errObj == nil || !strings.HasPrefix(errObj.Message, "import cycle detected") {
+		t.Fatalf("expected an import cycle error, got %v\n", errObj)
+	}
+
+	// modules are only run once, so both imports share the same count
+	expected := map[string]string{"a": "2", "b": "2", "caught": "IndexError"}
+	for name, val := range expected {
+		obj, ok := machine.globals.Get(name)
+		if !ok || obj.ToString() != val {
+			t.Errorf("expected %s to be %s, got %v\n", name, val, obj)
+		}
+	}
+
+	// the module cache belongs to the runtime, so another VM using it gets the same module
+	if _, ok := rt.Modules[filepath.Join(dir, "counter.yti")]; !ok || len(rt.ImportStack) != 0 {
+		t.Fatalf("expected the runtime to cache counter.yti with nothing left being imported, got %v and %v\n", rt.Modules, rt.ImportStack)
+	}
+
+	machine = NewVM(rt)
+	prog = parser.NewParser(lexer.NewLexer(`import "counter.yti" as c; var n = c.next();`)).Parse()
+	bytecode, _ = compiler.NewCompiler(mainFile).Compile(prog)
+
+	if errObj := machine.Run(bytecode); errObj != nil {
+		t.Fatal(errObj.ToString())
+	}
+
+	if n, _ := machine.globals.Get("n"); n == nil || n.ToString() != "3" {
+		t.Errorf("expected the cached module to carry on counting from 2, got %v\n", n)
+	}
+
+	// modules that aren't next to the importing file are found on the runtime's search path
+	rt = object.DefaultRuntime()
+	rt.SearchPath = []string{dir}
+	machine = NewVM(rt)
+	prog = parser.NewParser(lexer.NewLexer(`import "counter.yti" as c; var n = c.next();`)).Parse()
+	bytecode, _ = compiler.NewCompiler(filepath.Join(t.TempDir(), "other.yti")).Compile(prog)
+
+	if errObj := machine.Run(bytecode); errObj != nil {
+		t.Fatalf("expected the import to be found on the search path, got %s\n", errObj.ToString())
+	}
+
+	if n, _ := machine.globals.Get("n"); n == nil || n.ToString() != "1" {
+		t.Errorf("expected n to be 1, got %v\n", n)
+	}
+}
@@ -0,0 +1,186 @@
+// Package yetti runs Yetti programs from Go. An Interpreter keeps its global variables between runs,
+// so a host program can define values for a script, run it, and call the functions it defines.
+package yetti
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/MarkyMan4/yetti/ast"
+	"github.com/MarkyMan4/yetti/diagnostics"
+	"github.com/MarkyMan4/yetti/evaluator"
+	"github.com/MarkyMan4/yetti/lexer"
+	"github.com/MarkyMan4/yetti/object"
+	"github.com/MarkyMan4/yetti/parser"
+)
+
+// errors found while parsing a program, all of them are reported at once
+type SyntaxError struct {
+	File   string
+	Source string
+	Errors []*parser.ParseError
+}
+
+func (e *SyntaxError) Error() string {
+	msgs := make([]string, len(e.Errors))
+
+	for i, err := range e.Errors {
+		msgs[i] = diagnostics.Format(e.File, e.Source, err.Pos, err.Message)
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// error raised by a program that wasn't caught
+type RuntimeError struct {
+	Err    *object.ErrorObject
+	Source string // source of the file the error was raised in, used to show the line it happened on
+}
+
+// wrap an uncaught error, src is the source of file. If the error was raised in another file,
+// such as an imported module, the source of that file is read instead.
+func NewRuntimeError(errObj *object.ErrorObject, file string, src string) *RuntimeError {
+	if errObj.File != file {
+		src = ""
+
+		if contents, err := os.ReadFile(errObj.File); err == nil {
+			src = string(contents)
+		}
+	}
+
+	return &RuntimeError{Err: errObj, Source: src}
+}
+
+func (e *RuntimeError) Error() string {
+	return diagnostics.Format(e.Err.File, e.Source, e.Err.Pos, e.Err.ToString())
+}
+
+// returned when a program calls exit(), Code is the exit status it asked for
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// parse a program, file is only used in error messages
+func Parse(file string, src string) (*ast.Program, error) {
+	p := parser.NewParser(lexer.NewLexer(src))
+	prog := p.Parse()
+
+	if len(p.Errors) > 0 {
+		return nil, &SyntaxError{File: file, Source: src, Errors: p.Errors}
+	}
+
+	return prog, nil
+}
+
+type Interpreter struct {
+	env     *object.Environment
+	runtime *object.Runtime
+	sources map[string]string // source of each file that has been run, used for error messages
+}
+
+// create an interpreter that uses the standard streams of the process, use SetIO to change them
+func NewInterpreter() *Interpreter {
+	rt := object.DefaultRuntime()
+	env := object.NewEnvironment()
+	env.SetRuntime(rt)
+
+	return &Interpreter{env: env, runtime: rt, sources: map[string]string{}}
+}
+
+// set the streams programs read input from and write output to. Each interpreter has its own
+// streams, so output from one program doesn't end up in another.
+func (in *Interpreter) SetIO(stdin io.Reader, stdout io.Writer, stderr io.Writer) {
+	in.runtime.SetIO(stdin, stdout, stderr)
+}
+
+// set the maximum number of nested function calls, deeper recursion raises a RecursionError
+func (in *Interpreter) SetMaxCallDepth(depth int) {
+	in.runtime.MaxCallDepth = depth
+}
+
+// set the directories searched for imports that aren't found relative to the importing file
+func (in *Interpreter) SetSearchPath(dirs []string) {
+	in.runtime.SearchPath = dirs
+}
+
+// set the command-line arguments programs get from args()
+func (in *Interpreter) SetArgs(args []string) {
+	in.runtime.Args = args
+}
+
+// run a program, stopping at the first error that isn't caught
+func (in *Interpreter) Run(src string) error {
+	return in.run("", src)
+}
+
+// run the program in a file, imports in the program are resolved relative to the file
+func (in *Interpreter) RunFile(path string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading file %s: %w", path, err)
+	}
+
+	return in.run(path, string(src))
+}
+
+func (in *Interpreter) run(file string, src string) error {
+	prog, err := Parse(file, src)
+	if err != nil {
+		return err
+	}
+
+	in.env.SetFile(file)
+	in.sources[file] = src
+
+	for i := range prog.Statements {
+		if errObj, ok := evaluator.Eval(prog.Statements[i], in.env).(*object.ErrorObject); ok {
+			return in.runtimeError(errObj)
+		}
+	}
+
+	return nil
+}
+
+// close any files programs opened and didn't close, call this once the interpreter is no longer needed
+func (in *Interpreter) Close() error {
+	return in.runtime.CloseFiles()
+}
+
+// get the value of a global variable
+func (in *Interpreter) Get(name string) (object.Object, bool) {
+	return in.env.Get(name)
+}
+
+// define or replace a global variable, use ToObject to convert a Go value
+func (in *Interpreter) Set(name string, val object.Object) {
+	in.env.Set(name, val, true)
+}
+
+// call a function defined by a program that has been run, or a builtin function
+func (in *Interpreter) Call(fnName string, args ...object.Object) (object.Object, error) {
+	res := evaluator.CallFunction(fnName, args, in.env)
+
+	if errObj, ok := res.(*object.ErrorObject); ok {
+		return nil, in.runtimeError(errObj)
+	}
+
+	return res, nil
+}
+
+func (in *Interpreter) runtimeError(errObj *object.ErrorObject) error {
+	if errObj.Exit {
+		return &ExitError{Code: errObj.Code}
+	}
+
+	if src, ok := in.sources[errObj.File]; ok {
+		return &RuntimeError{Err: errObj, Source: src}
+	}
+
+	return NewRuntimeError(errObj, "", "")
+}
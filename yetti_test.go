@@ -0,0 +1,714 @@
+package yetti
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MarkyMan4/yetti/object"
+)
+
+func TestRunAndGet(t *testing.T) {
+	in := NewInterpreter()
+
+	if err := in.Run("var x = 1;"); err != nil {
+		t.Fatal(err)
+	}
+
+	// globals are kept between runs
+	if err := in.Run("x += 41;"); err != nil {
+		t.Fatal(err)
+	}
+
+	x, ok := in.Get("x")
+	if !ok || x.ToString() != "42" {
+		t.Fatalf("expected x to be 42, got %v\n", x)
+	}
+
+	if _, ok := in.Get("y"); ok {
+		t.Fatal("expected y to be undefined")
+	}
+}
+
+func TestSetAndCall(t *testing.T) {
+	in := NewInterpreter()
+
+	config, err := ToObject(map[string]interface{}{"name": "yetti", "sizes": []int{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in.Set("config", config)
+
+	err = in.Run(`
+		fun describe(prefix) {
+			return prefix + config.name + " " + string(length(config.sizes));
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := in.Call("describe", &object.StringObject{Value: "name: "})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.ToString() != "name: yetti 2" {
+		t.Fatalf("expected 'name: yetti 2', got %s\n", res.ToString())
+	}
+
+	// builtins can be called too
+	res, err = in.Call("length", &object.StringObject{Value: "abc"})
+	if err != nil || res.ToString() != "3" {
+		t.Fatalf("expected 3, got %v, %v\n", res, err)
+	}
+
+	if _, err := in.Call("missing"); err == nil {
+		t.Fatal("expected an error calling a function that isn't defined")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	in := NewInterpreter()
+
+	var syntaxErr *SyntaxError
+	err := in.Run("var x = ;\nvar y 1;")
+
+	if !errors.As(err, &syntaxErr) || len(syntaxErr.Errors) != 2 {
+		t.Fatalf("expected a syntax error with 2 errors, got %v\n", err)
+	}
+
+	var runtimeErr *RuntimeError
+	err = in.Run("var a = 1;\nvar b = a + true;")
+
+	if !errors.As(err, &runtimeErr) || runtimeErr.Err.Kind != object.TYPE_ERROR {
+		t.Fatalf("expected a TypeError, got %v\n", err)
+	}
+
+	expected := "2:9: TypeError: unsupported operator '+' for types INTEGER, BOOLEAN\nvar b = a + true;\n        ^"
+	if err.Error() != expected {
+		t.Fatalf("expected error\n%s\ngot\n%s\n", expected, err.Error())
+	}
+
+	if err := in.RunFile(filepath.Join(t.TempDir(), "missing.yti")); err == nil {
+		t.Fatal("expected an error running a file that doesn't exist")
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"lib.yti":  "fun double(n) { return n * 2; }",
+		"main.yti": "import \"lib.yti\" as lib;\nvar x = lib.double(21);\nvar y = lib.double(\"a\");",
+	}
+
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	in := NewInterpreter()
+	err := in.RunFile(filepath.Join(dir, "main.yti"))
+
+	// the error is reported in the module it was raised in, with that module's source
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Err.File != filepath.Join(dir, "lib.yti") || runtimeErr.Source != files["lib.yti"] {
+		t.Fatalf("expected an error from lib.yti, got %v\n", err)
+	}
+
+	x, _ := in.Get("x")
+	if x == nil || x.ToString() != "42" {
+		t.Fatalf("expected x to be 42, got %v\n", x)
+	}
+}
+
+func TestFunctionsKeepTheirFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a/a.yti":      "fun bad(n) {\n\treturn n + true;\n}\n\nfun load() {\n\timport \"helper.yti\" as h;\n\treturn h.value;\n}",
+		"a/helper.yti": "var value = \"from a\";",
+		"b/b.yti":      "var v = 1;\nvar w = 2;",
+	}
+
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	in := NewInterpreter()
+
+	for _, name := range []string{"a/a.yti", "b/b.yti"} {
+		if err := in.RunFile(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// functions from a.yti are still reported against a.yti after b.yti has run
+	_, err := in.Call("bad", &object.IntegerObject{Value: 1})
+
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Err.File != filepath.Join(dir, "a", "a.yti") || runtimeErr.Err.Pos.Line != 2 {
+		t.Fatalf("expected an error on line 2 of a.yti, got %v\n", err)
+	}
+
+	if !strings.Contains(err.Error(), "return n + true;") {
+		t.Errorf("expected the error to show the line from a.yti, got\n%s\n", err.Error())
+	}
+
+	// and their imports are resolved relative to a.yti
+	res, err := in.Call("load")
+	if err != nil || res.ToString() != "from a" {
+		t.Fatalf("expected the import to be resolved next to a.yti, got %v, %v\n", res, err)
+	}
+}
+
+func TestSetSearchPath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "lib.yti"), []byte("var name = \"lib\";"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	in := NewInterpreter()
+	in.SetSearchPath([]string{dir})
+
+	if err := in.Run(`import "lib.yti" as lib; var name = lib.name;`); err != nil {
+		t.Fatal(err)
+	}
+
+	if name, _ := in.Get("name"); name == nil || name.ToString() != "lib" {
+		t.Fatalf("expected name to be lib, got %v\n", name)
+	}
+
+	// each interpreter has its own search path
+	if err := NewInterpreter().Run(`import "lib.yti" as lib;`); err == nil {
+		t.Fatal("expected an error importing a module that isn't on the search path")
+	}
+}
+
+func TestSetIO(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "greet.yti")
+
+	if err := os.WriteFile(lib, []byte("fun greet(name) { print(\"hello\", name); }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a module imported by both interpreters writes to the streams of the one calling it
+	var outs [2]bytes.Buffer
+	for i, name := range []string{"a", "b"} {
+		in := NewInterpreter()
+		in.SetIO(strings.NewReader(name+"\n"), &outs[i], &outs[i])
+
+		err := in.Run("import \"" + lib + "\" as g;\ng.greet(input());")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if outs[0].String() != "hello a\n" || outs[1].String() != "hello b\n" {
+		t.Errorf("expected each interpreter to write its own output, got %q and %q\n", outs[0].String(), outs[1].String())
+	}
+}
+
+func TestStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	in := NewInterpreter()
+	in.SetIO(strings.NewReader("name\r\nfirst\nsecond\nthird\nrest\nof input"), &stdout, &stderr)
+
+	// every builtin reads from the same buffer, so no input is lost between calls
+	err := in.Run(`
+		var name = input("name? ");
+		var first = readLine();
+		var two = [];
+
+		for(line in [1, 2]) {
+			two = append(two, readLine());
+		}
+
+		var rest = readAll();
+		var done = readLine() == null;
+		var empty = 0;
+
+		for(line in lines()) {
+			empty += 1;
+		}
+
+		eprint("done", done);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"name":  "name",
+		"first": "first",
+		"two":   "[second,third]",
+		"rest":  "rest\nof input",
+		"done":  "true",
+		"empty": "0",
+	}
+
+	for name, val := range expected {
+		obj, ok := in.Get(name)
+		if !ok || obj.ToString() != val {
+			t.Errorf("expected %s to be %q, got %v\n", name, val, obj)
+		}
+	}
+
+	if stdout.String() != "name? " || stderr.String() != "done true\n" {
+		t.Errorf("expected stdout %q and stderr %q, got %q and %q\n", "name? ", "done true\n", stdout.String(), stderr.String())
+	}
+
+	in.SetIO(strings.NewReader("a\nb\n\nc"), &stdout, &stderr)
+
+	if err := in.Run("var all = []; for(line in lines()) { all = append(all, line); }"); err != nil {
+		t.Fatal(err)
+	}
+
+	if all, _ := in.Get("all"); all.ToString() != "[a,b,,c]" {
+		t.Errorf("expected lines to be [a,b,,c], got %s\n", all.ToString())
+	}
+}
+
+// reader that gives one chunk of input per read, counting the reads so far
+type chunkReader struct {
+	chunks []string
+	reads  int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if r.reads >= len(r.chunks) {
+		return 0, io.EOF
+	}
+
+	r.reads++
+
+	return copy(p, r.chunks[r.reads-1]), nil
+}
+
+// writer that records how much input had been read each time the program finished printing a line
+type readsWriter struct {
+	input *chunkReader
+	reads []int
+}
+
+func (w *readsWriter) Write(p []byte) (int, error) {
+	if bytes.HasSuffix(p, []byte("\n")) {
+		w.reads = append(w.reads, w.input.reads)
+	}
+
+	return len(p), nil
+}
+
+func TestLinesStreamsInput(t *testing.T) {
+	stdin := &chunkReader{chunks: []string{"a\n", "b\n", "c\n"}}
+	stdout := &readsWriter{input: stdin}
+
+	in := NewInterpreter()
+	in.SetIO(stdin, stdout, io.Discard)
+
+	// each line is handled before the next one is read, so a script can be used in a pipeline
+	if err := in.Run(`for(line in lines()) { print(line); }`); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(stdout.reads) != "[1 2 3]" {
+		t.Errorf("expected one read before each line is printed, got reads %v\n", stdout.reads)
+	}
+}
+
+func TestArgsAndExit(t *testing.T) {
+	t.Setenv("YETTI_TEST_VAR", "from the host")
+
+	in := NewInterpreter()
+	in.SetArgs([]string{"-v", "file.txt"})
+
+	err := in.Run(`
+		var a = args();
+		var home = getenv("YETTI_TEST_VAR");
+		var missing = getenv("YETTI_TEST_MISSING") == null;
+		setenv("YETTI_TEST_VAR", "from the script");
+		var env = environ();
+
+		// exiting can't be caught
+		try {
+			exit(4);
+		} catch(e) {
+			var caught = true;
+		}
+
+		var after = true;
+	`)
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 4 {
+		t.Fatalf("expected the program to exit with status 4, got %v\n", err)
+	}
+
+	expected := map[string]string{
+		"a":       "[-v,file.txt]",
+		"home":    "from the host",
+		"missing": "true",
+	}
+
+	for name, val := range expected {
+		obj, ok := in.Get(name)
+		if !ok || obj.ToString() != val {
+			t.Errorf("expected %s to be %q, got %v\n", name, val, obj)
+		}
+	}
+
+	env, _ := in.Get("env")
+	if val, ok := env.(*object.MapObject).Get(&object.StringObject{Value: "YETTI_TEST_VAR"}); !ok || val.ToString() != "from the script" {
+		t.Errorf("expected environ to include the variable set by the script, got %v\n", val)
+	}
+
+	for _, name := range []string{"caught", "after"} {
+		if _, ok := in.Get(name); ok {
+			t.Errorf("expected %s to be undefined after exiting\n", name)
+		}
+	}
+}
+
+func TestExitStatusRange(t *testing.T) {
+	in := NewInterpreter()
+
+	err := in.Run(`
+		var caught = [];
+
+		for(code in [256, -1]) {
+			try {
+				exit(code);
+			} catch(e) {
+				caught = append(caught, e.kind);
+			}
+		}
+
+		exit(255);
+	`)
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 255 {
+		t.Fatalf("expected the program to exit with status 255, got %v\n", err)
+	}
+
+	if obj, _ := in.Get("caught"); obj == nil || obj.ToString() != "[ValueError,ValueError]" {
+		t.Errorf("expected out of range statuses to raise catchable value errors, got %v\n", obj)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+
+	in := NewInterpreter()
+	in.Set("path", &object.StringObject{Value: path})
+	in.Set("alias", &object.StringObject{Value: filepath.Dir(path) + string(filepath.Separator) + "." + string(filepath.Separator) + "out.txt"})
+
+	err := in.Run(`
+		var out = openFile(path, "w");
+		out.writeLine("first");
+		out.write(1);
+		out.write(2);
+		out.flush();
+
+		var log = openFile(path, "a");
+		log.writeLine("");
+		log.writeLine("appended");
+		log.close();
+
+		var caught = [];
+
+		try {
+			log.writeLine("closed");
+		} catch(e) {
+			caught = append(caught, e.kind);
+		}
+
+		try {
+			openFile(path, "rw");
+		} catch(e) {
+			caught = append(caught, e.kind);
+		}
+
+		try {
+			openFile(path + ".missing");
+		} catch(e) {
+			caught = append(caught, e.kind);
+		}
+
+		var f = openFile(path);
+		var lines = [];
+
+		for(line in f.lines()) {
+			lines = append(lines, line);
+		}
+
+		// copying a file onto itself or copying a directory is rejected before anything is truncated
+		for(dst in [path, alias]) {
+			try {
+				copyFile(path, dst);
+			} catch(e) {
+				caught = append(caught, e.kind);
+			}
+		}
+
+		try {
+			copyFile(dir(path), path + ".copy");
+		} catch(e) {
+			caught = append(caught, e.kind);
+		}
+
+		// files left open are closed when the interpreter is closed, writing anything still buffered
+		var tail = openFile(path, "a");
+		tail.write("unflushed");
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, val := range map[string]string{"lines": "[first,12,appended]", "caught": "[IOError,ValueError,FileNotFoundError,IOError,IOError,IOError]"} {
+		if obj, _ := in.Get(name); obj == nil || obj.ToString() != val {
+			t.Errorf("expected %s to be %s, got %v\n", name, val, obj)
+		}
+	}
+
+	if err := in.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "first\n12\nappended\nunflushed" {
+		t.Errorf("expected the file to keep everything written to it, got %q\n", content)
+	}
+
+	if _, err := os.Stat(path + ".copy"); !os.IsNotExist(err) {
+		t.Errorf("expected copying a directory not to create the destination, got %v\n", err)
+	}
+}
+
+func TestFilesystem(t *testing.T) {
+	in := NewInterpreter()
+
+	err := in.Run(`
+		var root = tempDir("yetti-test-*");
+		mkdir(join(root, "a", "b"));
+
+		var f = openFile(join(root, "a", "one.txt"), "w");
+		f.write("hello");
+		f.close();
+
+		copyFile(join(root, "a", "one.txt"), join(root, "a", "b", "two.txt"));
+		rename(join(root, "a", "b", "two.txt"), join(root, "a", "b", "three.log"));
+
+		var listed = listDir(join(root, "a"));
+		var walked = [];
+		var globbed = [];
+
+		for(path in walk(root)) {
+			walked = append(walked, base(path));
+		}
+
+		for(path in glob(join(root, "*", "*.txt"))) {
+			globbed = append(globbed, base(dir(path)) + "/" + base(path));
+		}
+
+		var info = stat(join(root, "a", "b", "three.log"));
+		var size = info.size;
+		var checks = [exists(root), isDir(root), isDir(join(root, "a", "one.txt")), exists(join(root, "nope"))];
+		var extension = ext(join(root, "a", "one.txt"));
+		var caught = [];
+
+		try {
+			listDir(join(root, "missing"));
+		} catch(e) {
+			caught = append(caught, e.kind);
+		}
+
+		try {
+			remove(join(root, "a"));
+		} catch(e) {
+			caught = append(caught, e.kind);
+		}
+
+		try {
+			glob("[");
+		} catch(e) {
+			caught = append(caught, e.kind);
+		}
+
+		var temp = tempFile("yetti-*.txt");
+		var tempExt = ext(temp);
+		remove(temp);
+
+		remove(root, true);
+		var removed = !exists(root) && !exists(temp);
+		var cwd = abs(".");
+	`)
+
+	if root, ok := in.Get("root"); ok {
+		defer os.RemoveAll(root.ToString())
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ := os.Getwd()
+	expected := map[string]string{
+		"listed":    "[b,one.txt]",
+		"walked":    "[a,b,three.log,one.txt]",
+		"globbed":   "[a/one.txt]",
+		"size":      "5",
+		"checks":    "[true,true,false,false]",
+		"extension": ".txt",
+		"caught":    "[FileNotFoundError,IOError,ValueError]",
+		"tempExt":   ".txt",
+		"removed":   "true",
+		"cwd":       wd,
+	}
+
+	for name, val := range expected {
+		obj, ok := in.Get(name)
+		if !ok || obj.ToString() != val {
+			t.Errorf("expected %s to be %s, got %v\n", name, val, obj)
+		}
+	}
+
+	info, _ := in.Get("info")
+	for _, key := range []string{"name", "mtime", "mode", "isDir"} {
+		if _, ok := info.(*object.MapObject).Get(&object.StringObject{Value: key}); !ok {
+			t.Errorf("expected stat to return %s\n", key)
+		}
+	}
+}
+
+func TestIndependentInterpreters(t *testing.T) {
+	src := `
+		fun down(n) {
+			if (n == 0) {
+				return 0;
+			}
+
+			return down(n - 1);
+		}
+
+		var res = "ok";
+
+		try {
+			down(depth);
+		} catch(e) {
+			res = e.kind;
+		}
+	`
+
+	// each interpreter counts its own calls against its own limit, even when they run at the same time
+	depths := []int64{50, 99, 100, 200}
+	results := make([]string, len(depths))
+	done := make(chan bool)
+
+	for i := range results {
+		go func(i int) {
+			defer func() { done <- true }()
+
+			in := NewInterpreter()
+			in.SetMaxCallDepth(100)
+			in.Set("depth", &object.IntegerObject{Value: depths[i]})
+
+			if err := in.Run(src); err != nil {
+				results[i] = err.Error()
+				return
+			}
+
+			res, _ := in.Get("res")
+			results[i] = res.ToString()
+		}(i)
+	}
+
+	for range results {
+		<-done
+	}
+
+	expected := []string{"ok", "ok", "RecursionError", "RecursionError"}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Errorf("expected interpreter %d to give %s, got %s\n", i, expected[i], results[i])
+		}
+	}
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{3, int64(3)},
+		{uint8(7), int64(7)},
+		{float32(1.5), 1.5},
+		{"abc", "abc"},
+		{[]string{"a", "b"}, []interface{}{"a", "b"}},
+		{map[string]int{"b": 2, "a": 1}, map[string]interface{}{"a": int64(1), "b": int64(2)}},
+		{[]interface{}{1, []int{2}, nil}, []interface{}{int64(1), []interface{}{int64(2)}, nil}},
+	}
+
+	for _, tt := range tests {
+		obj, err := ToObject(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := FromObject(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(res, tt.expected) {
+			t.Errorf("expected %#v to convert to %#v, got %#v\n", tt.input, tt.expected, res)
+		}
+	}
+
+	if _, err := ToObject(map[int]string{1: "a"}); err == nil {
+		t.Error("expected an error converting a map with int keys")
+	}
+
+	if _, err := ToObject(uint64(math.MaxInt64) + 1); err == nil {
+		t.Error("expected an error converting a uint64 larger than the largest integer")
+	}
+
+	if obj, err := ToObject(uint64(math.MaxInt64)); err != nil || obj.ToString() != "9223372036854775807" {
+		t.Errorf("expected the largest integer to convert, got %v, %v\n", obj, err)
+	}
+
+	if _, err := ToObject(make(chan int)); err == nil {
+		t.Error("expected an error converting a channel")
+	}
+
+	mapObj := object.NewMapObject()
+	mapObj.Set(&object.IntegerObject{Value: 1}, &object.NullObject{})
+
+	if _, err := FromObject(mapObj); err == nil {
+		t.Error("expected an error converting a map with an integer key")
+	}
+
+	if _, err := FromObject(&object.FunctionObject{}); err == nil {
+		t.Error("expected an error converting a function")
+	}
+}